@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"fmt"
+	"nvm_manager_go/utils"
+	"regexp"
+	"slices"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+var aliasDelete bool
+
+var AliasCmd = &cobra.Command{
+	Use:   "alias [name] [version]",
+	Short: "Name versions, e.g. 'nea alias work 0.9.5'",
+	Long: `Give a name to a version, usable wherever a version is: install, use,
+clean and .nvim-version files. An alias can stand for anything those
+accept, including 'nightly' and ranges such as ^0.10, which are resolved
+each time it is used.
+
+  nea alias work 0.9.5      define or change an alias
+  nea alias work            show what it stands for
+  nea alias                 list every alias
+  nea alias -d work         remove it`,
+	Args: cobra.MaximumNArgs(2),
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		utils.Setup()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+		switch {
+		case aliasDelete:
+			if len(args) != 1 {
+				err = fmt.Errorf("give the name of the alias to remove")
+				break
+			}
+			err = removeAlias(args[0])
+		case len(args) == 2:
+			err = setAlias(args[0], args[1])
+		default:
+			err = listAliases(args)
+		}
+		if err != nil {
+			fmt.Println("Error:", err)
+		}
+	},
+}
+
+func init() {
+	AliasCmd.Flags().BoolVarP(&aliasDelete, "delete", "d", false, "remove the alias")
+}
+
+// aliasName is a word starting with a letter, setAlias also rejects the
+// ones that read as a version such as v0.10
+var aliasName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.-]*$`)
+
+func setAlias(name, version string) error {
+	if !aliasName.MatchString(name) {
+		return fmt.Errorf("'%s' can't be an alias, use a letter followed by letters, digits, '.', '_' or '-'", name)
+	}
+	if utils.IsVersionRange(name) || utils.IsVersion(name) {
+		return fmt.Errorf("'%s' reads as a version, it can't be an alias", name)
+	}
+	if slices.Contains([]string{"nightly", "stable", "latest", "previous-stable", "all"}, name) {
+		return fmt.Errorf("'%s' is a keyword, it can't be an alias", name)
+	}
+	if _, ok := utils.FindLinkedVersion(name); ok {
+		return fmt.Errorf("'%s' is a linked version, it can't be an alias", name)
+	}
+
+	aliases, err := utils.ReadAliases()
+	if err != nil {
+		return err
+	}
+	if _, ok := aliases[version]; ok {
+		return fmt.Errorf("'%s' is an alias itself, aliases can't point at other aliases", version)
+	}
+	if utils.IsVersionRange(version) {
+		if _, err = utils.ParseVersionRange(version); err != nil {
+			return err
+		}
+	}
+
+	previous, existed := aliases[name]
+	aliases[name] = version
+	if err = utils.WriteAliases(aliases); err != nil {
+		return err
+	}
+	if existed && previous != version {
+		fmt.Printf("%s now stands for %s (was %s)\n", name, version, previous)
+	} else {
+		fmt.Printf("%s stands for %s\n", name, version)
+	}
+	return nil
+}
+
+func removeAlias(name string) error {
+	aliases, err := utils.ReadAliases()
+	if err != nil {
+		return err
+	}
+	if _, ok := aliases[name]; !ok {
+		return fmt.Errorf("no alias named '%s'", name)
+	}
+	delete(aliases, name)
+	if err = utils.WriteAliases(aliases); err != nil {
+		return err
+	}
+	fmt.Printf("Removed alias %s\n", name)
+	return nil
+}
+
+func listAliases(args []string) error {
+	aliases, err := utils.ReadAliases()
+	if err != nil {
+		return err
+	}
+	if len(args) == 1 {
+		version, ok := aliases[args[0]]
+		if !ok {
+			return fmt.Errorf("no alias named '%s'", args[0])
+		}
+		fmt.Println(version)
+		return nil
+	}
+	if len(aliases) == 0 {
+		fmt.Println("No aliases, define one with 'nea alias <name> <version>'.")
+		return nil
+	}
+
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%-16s %s\n", name, aliases[name])
+	}
+	return nil
+}
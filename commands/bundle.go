@@ -0,0 +1,301 @@
+package commands
+
+import (
+	"fmt"
+	"nvm_manager_go/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var bundleOutput string
+
+var BundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Move installed versions to machines without internet access",
+	Long: `Pack installed versions into a single tar file and install them on
+another machine, e.g. an air-gapped build host. The bundle carries the
+registry entries, so nightlies keep their node ID and creation date and
+roll back like anywhere else, along with a SHA-256 of every version.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		utils.Setup()
+	},
+}
+
+var bundleCreateCmd = &cobra.Command{
+	Use:   "create <versions...>",
+	Short: "Pack installed versions into a bundle",
+	Long: `Pack installed versions into a bundle. Versions can be:
+- x.y.z: an installed stable version
+- stable: the latest stable release, which must be installed
+- nightly: the newest installed nightly
+- YYYY-MM-DD: the nightly built that day
+- all: every installed stable and nightly (linked versions are never packed)`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := createBundle(args, bundleOutput); err != nil {
+			fmt.Println("Error:", err)
+		}
+	},
+}
+
+var bundleInstallCmd = &cobra.Command{
+	Use:   "install <bundle>",
+	Short: "Verify and install the versions of a bundle",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := installBundle(args[0]); err != nil {
+			fmt.Println("Error:", err)
+		}
+	},
+}
+
+func init() {
+	bundleCreateCmd.Flags().StringVarP(&bundleOutput, "output", "o", "nea-bundle.tar", "file to write the bundle to")
+	BundleCmd.AddCommand(bundleCreateCmd, bundleInstallCmd)
+}
+
+func createBundle(selectors []string, output string) error {
+	versions, err := selectBundleVersions(selectors)
+	if err != nil {
+		return err
+	}
+
+	workDir, err := os.MkdirTemp("", "nea-bundle-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	manifest := utils.NewBundleManifest(time.Now().UTC().Format(time.RFC3339))
+	for i, version := range versions {
+		fmt.Printf("Packing %s...\n", bundleLabel(version))
+
+		// The binary is stored relative to the version's directory, which is
+		// chosen again on the machine installing the bundle
+		binary, err := filepath.Rel(version.Directory, version.Binary)
+		if err != nil || strings.HasPrefix(binary, "..") {
+			return fmt.Errorf("binary of %s is outside of its directory", bundleLabel(version))
+		}
+		entry := version
+		entry.Binary = binary
+		entry.Directory = ""
+
+		archivePath := filepath.Join(workDir, fmt.Sprintf("%d-%s.tar.gz", i, entry.Kind))
+		if err = utils.CreateTarGz(version.Directory, archivePath); err != nil {
+			return fmt.Errorf("failed to pack %s: %w", bundleLabel(version), err)
+		}
+		if err = manifest.AddArchive(entry, archivePath); err != nil {
+			return err
+		}
+	}
+
+	if err = utils.WriteBundle(output, manifest, workDir); err != nil {
+		return err
+	}
+	color.Green("Wrote %d version(s) to %s", len(versions), output)
+	return nil
+}
+
+// selectBundleVersions resolves the create arguments to registry entries,
+// each with its directory and binary filled in
+func selectBundleVersions(selectors []string) ([]utils.VersionInfo, error) {
+	nightlies, err := utils.ReadVersionsInfo()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read versions info: %w", err)
+	}
+	stables, _ := utils.GetLocalStableVersions()
+
+	var selected []utils.VersionInfo
+	seen := make(map[string]bool)
+	add := func(version utils.VersionInfo) {
+		if !seen[version.Directory] {
+			seen[version.Directory] = true
+			selected = append(selected, version)
+		}
+	}
+	addStable := func(version string) error {
+		entry, err := installedStable(version)
+		if err != nil {
+			return err
+		}
+		add(entry)
+		return nil
+	}
+	addNightly := func(version utils.VersionInfo) error {
+		binary, err := nightlyBinary(version)
+		if err != nil {
+			return err
+		}
+		version.Binary = binary
+		version.Kind = utils.KindNightly
+		add(version)
+		return nil
+	}
+
+	for _, selector := range selectors {
+		switch {
+		case selector == "all":
+			for _, stable := range stables {
+				if err = addStable(stable); err != nil {
+					return nil, err
+				}
+			}
+			for _, nightly := range nightlies {
+				if err = addNightly(nightly); err != nil {
+					return nil, err
+				}
+			}
+		case selector == "stable":
+			var version string
+			if version, err = utils.ResolveInstalledVersion(stableSource, selector, notef); err == nil {
+				err = addStable(version)
+			}
+		case selector == "nightly":
+			if len(nightlies) == 0 {
+				return nil, fmt.Errorf("no nightly versions installed")
+			}
+			err = addNightly(nightlies[0])
+		case strings.HasPrefix(selector, "20"):
+			index, found := findNightlyVersion(nightlies, selector)
+			if !found {
+				return nil, fmt.Errorf("no nightly from %s installed", selector)
+			}
+			err = addNightly(nightlies[index])
+		default:
+			err = addStable(strings.TrimPrefix(selector, "v"))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return selected, nil
+}
+
+// installedStable returns the registry entry of an installed stable,
+// rebuilding it for stables installed before they were registered
+func installedStable(version string) (utils.VersionInfo, error) {
+	binary, err := stableBinary(version)
+	if err != nil {
+		return utils.VersionInfo{}, err
+	}
+	entry, ok := utils.FindStableVersion(version)
+	if !ok {
+		entry = utils.VersionInfo{Kind: utils.KindStable, Version: version}
+	}
+	entry.Directory = filepath.Join(targetDirStable, version)
+	entry.Binary = binary
+	return entry, nil
+}
+
+func installBundle(path string) error {
+	workDir, err := os.MkdirTemp("", "nea-bundle-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	manifest, err := utils.ReadBundle(path, workDir)
+	if err != nil {
+		return err
+	}
+	if platform := utils.CurrentPlatform(); manifest.Platform.OS != platform.OS || manifest.Platform.Arch != platform.Arch {
+		return fmt.Errorf("bundle was created on %s/%s, this is %s/%s",
+			manifest.Platform.OS, manifest.Platform.Arch, platform.OS, platform.Arch)
+	}
+	fmt.Printf("Bundle verified, %d version(s) created on %s\n", len(manifest.Entries), manifest.CreatedAt)
+
+	installed := 0
+	for _, entry := range manifest.Entries {
+		archivePath := filepath.Join(workDir, filepath.Base(entry.Archive))
+		done, err := installBundleEntry(entry.Version, archivePath)
+		if err != nil {
+			return fmt.Errorf("failed to install %s: %w", bundleLabel(entry.Version), err)
+		}
+		if done {
+			installed++
+			color.Green("Installed %s", bundleLabel(entry.Version))
+		} else {
+			color.Yellow("%s is already installed", bundleLabel(entry.Version))
+		}
+	}
+
+	if installed > 0 {
+		if _, err = utils.DetermineCurrentVersion(); err != nil {
+			fmt.Println("No version is in use yet, pick one with 'nea use'.")
+		}
+	}
+	return nil
+}
+
+// installBundleEntry extracts a packed version where nea would have installed
+// it and registers it. It returns false when the version is already there.
+func installBundleEntry(version utils.VersionInfo, archivePath string) (bool, error) {
+	// The manifest comes from elsewhere, nothing in it may lead outside of
+	// nea's directories
+	switch {
+	case version.Kind == utils.KindCustom:
+		return false, fmt.Errorf("linked versions can't be installed from a bundle")
+	case version.IsNightly() && (version.NodeID == "" || version.CreatedAt == ""):
+		return false, fmt.Errorf("nightly entry without a node ID or creation date")
+	case !version.IsNightly() && !utils.IsVersion(version.Version):
+		return false, fmt.Errorf("invalid stable version '%s'", version.Version)
+	case !filepath.IsLocal(version.Binary):
+		return false, fmt.Errorf("binary %s is outside of the version's directory", version.Binary)
+	}
+
+	var targetDir string
+	var err error
+	if version.IsNightly() {
+		if isVersionInstalled(version.NodeID, version.CreatedAt) {
+			return false, nil
+		}
+		if targetDir, err = utils.CreateTargetDirectory(version.CreatedAt); err != nil {
+			return false, fmt.Errorf("failed to create target directory: %w", err)
+		}
+	} else {
+		version.Version = strings.TrimPrefix(version.Version, "v")
+		targetDir = filepath.Join(targetDirStable, version.Version)
+		if _, err = os.Stat(targetDir); err == nil {
+			return false, nil
+		}
+		if err = os.MkdirAll(targetDir, 0o755); err != nil {
+			return false, fmt.Errorf("failed to create target directory: %w", err)
+		}
+	}
+
+	if _, err = utils.ExtractTarGz(archivePath, targetDir); err != nil {
+		os.RemoveAll(targetDir)
+		return false, err
+	}
+
+	version.Directory = targetDir
+	version.Binary = filepath.Join(targetDir, version.Binary)
+	if _, err = os.Stat(version.Binary); err != nil {
+		os.RemoveAll(targetDir)
+		return false, fmt.Errorf("nvim binary missing from the bundle: %w", err)
+	}
+
+	if version.IsNightly() {
+		registryMu.Lock()
+		err = updateVersionsInfo(utils.ReleaseInfo{NodeID: version.NodeID, CreatedAt: version.CreatedAt}, version)
+		registryMu.Unlock()
+	} else {
+		err = utils.RegisterStableVersion(version)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to update versions info: %w", err)
+	}
+	return true, nil
+}
+
+func bundleLabel(version utils.VersionInfo) string {
+	if version.IsNightly() {
+		return "nightly " + version.CreatedAt
+	}
+	return version.Version
+}
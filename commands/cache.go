@@ -0,0 +1,145 @@
+package commands
+
+import (
+	"fmt"
+	"nvm_manager_go/utils"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pruneMaxSizeMB int
+	pruneOlderThan int
+)
+
+var CacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the download cache",
+	Long: `Downloaded archives are kept in a cache keyed by URL and SHA-256, so
+reinstalling a version doesn't download it again and works offline.
+The cache is pruned to artifactCacheLimitMB (config.json, 2048 by default,
+-1 for no limit) whenever something is added to it.`,
+}
+
+var cacheLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List cached downloads",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := listCache(); err != nil {
+			fmt.Println("Error:", err)
+		}
+	},
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove the least recently used downloads",
+	Long: `Remove cached downloads unused for longer than --older-than days, then the
+least recently used ones until the cache fits in --max-size MB (the
+configured limit by default).`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := pruneCache(); err != nil {
+			fmt.Println("Error:", err)
+		}
+	},
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove every cached download and all cached release metadata",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := utils.ClearCache(); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		color.Green("Cleared %s", utils.CacheDir())
+	},
+}
+
+func init() {
+	cachePruneCmd.Flags().IntVar(&pruneMaxSizeMB, "max-size", 0, "size to prune the cache to, in MB")
+	cachePruneCmd.Flags().IntVar(&pruneOlderThan, "older-than", 0, "remove downloads unused for this many days")
+	CacheCmd.AddCommand(cacheLsCmd, cachePruneCmd, cacheClearCmd)
+}
+
+func listCache() error {
+	artifacts, err := utils.ListArtifacts()
+	if err != nil {
+		return err
+	}
+	if len(artifacts) == 0 {
+		fmt.Println("The download cache is empty.")
+		return nil
+	}
+
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetHeader([]string{"Name", "Size", "Last Used", "SHA-256", "URL"})
+
+	var total int64
+	counted := make(map[string]bool)
+	for _, artifact := range artifacts {
+		table.Append([]string{
+			artifact.Name,
+			formatSize(artifact.Size),
+			artifact.LastUsed.Local().Format("2006-01-02 15:04"),
+			artifact.SHA256[:12],
+			artifact.URL,
+		})
+		if !counted[artifact.SHA256] {
+			counted[artifact.SHA256] = true
+			total += artifact.Size
+		}
+	}
+	table.Render()
+	fmt.Println(tableString.String())
+
+	limit := "no limit"
+	if bytes := utils.ArtifactCacheLimit(); bytes > 0 {
+		limit = "limit " + formatSize(bytes)
+	}
+	fmt.Printf("Total: %s (%s) in %s\n", formatSize(total), limit, utils.CacheDir())
+	return nil
+}
+
+func pruneCache() error {
+	maxSize := utils.ArtifactCacheLimit()
+	if pruneMaxSizeMB > 0 {
+		maxSize = int64(pruneMaxSizeMB) << 20
+	}
+	maxAge := time.Duration(pruneOlderThan) * 24 * time.Hour
+
+	removed, err := utils.PruneArtifacts(maxSize, maxAge)
+	if err != nil {
+		return err
+	}
+	if len(removed) == 0 {
+		fmt.Println("Nothing to prune.")
+		return nil
+	}
+	for _, artifact := range removed {
+		fmt.Printf("Removed %s (%s)\n", artifact.Name, formatSize(artifact.Size))
+	}
+	return nil
+}
+
+// formatSize prints a byte count the way humans read it
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,340 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"nvm_manager_go/utils"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"golang.org/x/mod/semver"
+)
+
+var (
+	changelogBreaking bool
+	changelogFormat   string
+)
+
+var ChangelogCmd = &cobra.Command{
+	Use:   "changelog [from] [to]",
+	Short: "Show what changed between two versions",
+	Long: `Show what changed between two versions before updating.
+
+Between two stable versions, the GitHub release notes of every release
+after 'from' up to 'to' are shown. When either side is a nightly, it is
+the range of commits between the builds, from the commit in their
+'nvim --version'.
+
+A version is a stable version number, 'stable' (the latest release),
+'nightly' (the newest installed nightly), the date of an installed
+nightly or a linked version. 'from' defaults to the version in use and
+'to' to the latest release of the same channel.
+
+  nea changelog                       what an update would bring
+  nea changelog 0.10.4 0.11.0
+  nea changelog 2025-03-01 nightly
+  nea changelog 0.10.0 --breaking --format json`,
+	Args: cobra.MaximumNArgs(2),
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		utils.Setup()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := showChangelog(args); err != nil {
+			fmt.Println("Error:", err)
+		}
+	},
+}
+
+func init() {
+	ChangelogCmd.Flags().BoolVar(&changelogBreaking, "breaking", false, "only show what mentions breaking changes")
+	ChangelogCmd.Flags().StringVar(&changelogFormat, "format", "text", "output format: text or json")
+}
+
+// changelogEnd is one side of a changelog
+type changelogEnd struct {
+	label string
+	// ref is what GitHub compares, a tag or a commit
+	ref string
+	// version is the stable version number, empty for development builds
+	version string
+}
+
+func (e changelogEnd) String() string {
+	if e.version != "" || e.ref == "" {
+		return e.label
+	}
+	return fmt.Sprintf("%s (%s)", e.label, shortSHA(e.ref))
+}
+
+// changelog is what 'nea changelog --format json' prints
+type changelog struct {
+	From         string               `json:"from"`
+	To           string               `json:"to"`
+	Releases     []utils.ReleaseNotes `json:"releases,omitempty"`
+	Commits      []utils.Commit       `json:"commits,omitempty"`
+	TotalCommits int                  `json:"total_commits,omitempty"`
+	CompareURL   string               `json:"compare_url,omitempty"`
+}
+
+func showChangelog(args []string) error {
+	if changelogFormat != "text" && changelogFormat != "json" {
+		return fmt.Errorf("unknown format '%s', expected text or json", changelogFormat)
+	}
+
+	var from, to changelogEnd
+	var err error
+	if len(args) > 0 {
+		from, err = resolveChangelogEnd(args[0])
+	} else {
+		current, currentErr := utils.DetermineCurrentVersion()
+		if currentErr != nil {
+			return fmt.Errorf("no version in use, pass the versions to compare: %w", currentErr)
+		}
+		from, err = resolveChangelogEnd(current)
+	}
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(args) > 1:
+		to, err = resolveChangelogEnd(args[1])
+	case from.version != "":
+		to, err = resolveChangelogEnd("stable")
+	default:
+		to, err = latestNightlyEnd()
+	}
+	if err != nil {
+		return err
+	}
+
+	result := changelog{From: from.String(), To: to.String()}
+	if from.version != "" && to.version != "" {
+		err = collectReleaseNotes(&result, from.version, to.version)
+	} else {
+		err = collectCommits(&result, from, to)
+	}
+	if err != nil {
+		return err
+	}
+
+	if changelogFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+	printChangelog(result, from.version == "" || to.version == "")
+	return nil
+}
+
+// resolveChangelogEnd turns an argument into the tag or commit it stands for
+func resolveChangelogEnd(arg string) (changelogEnd, error) {
+	if linked, ok := utils.FindLinkedVersion(arg); ok {
+		return binaryEnd(linked.Name, linked.Binary)
+	}
+
+	switch arg {
+	case "nightly":
+		versions, err := utils.ReadVersionsInfo()
+		if err != nil || len(versions) == 0 {
+			return changelogEnd{}, fmt.Errorf("no nightly versions installed, run 'nea install nightly'")
+		}
+		return installedNightlyEnd(versions[0])
+	case "stable":
+		version, err := utils.FetchLatestStable(stableSource)
+		if err != nil {
+			return changelogEnd{}, fmt.Errorf("failed to find the latest stable release: %w", err)
+		}
+		return changelogEnd{label: version, ref: "v" + version, version: version}, nil
+	}
+
+	if versions, err := utils.ReadVersionsInfo(); err == nil {
+		for _, version := range versions {
+			if nightlyDate(version) == arg {
+				return installedNightlyEnd(version)
+			}
+		}
+	}
+
+	version := strings.TrimPrefix(arg, "v")
+	if !semver.IsValid("v"+version) || semver.Prerelease("v"+version) != "" {
+		return changelogEnd{}, fmt.Errorf("'%s' is neither a stable version, an installed nightly nor a linked version", arg)
+	}
+	return changelogEnd{label: version, ref: "v" + version, version: version}, nil
+}
+
+func installedNightlyEnd(version utils.VersionInfo) (changelogEnd, error) {
+	binary, err := nightlyBinary(version)
+	if err != nil {
+		return changelogEnd{}, err
+	}
+	return binaryEnd("nightly "+nightlyDate(version), binary)
+}
+
+// binaryEnd asks a binary which commit, or which release, it was built from
+func binaryEnd(label, binary string) (changelogEnd, error) {
+	version, err := utils.NvimVersion(binary)
+	if err != nil {
+		return changelogEnd{}, err
+	}
+	if commit := utils.CommitFromVersion(version); commit != "" {
+		return changelogEnd{label: label, ref: commit}, nil
+	}
+	if semver.IsValid(version) && semver.Prerelease(version) == "" {
+		return changelogEnd{label: label, ref: version, version: strings.TrimPrefix(version, "v")}, nil
+	}
+	return changelogEnd{}, fmt.Errorf("cannot tell which commit %s (%s) was built from", label, version)
+}
+
+// latestNightlyEnd is the nightly build currently published
+func latestNightlyEnd() (changelogEnd, error) {
+	end := changelogEnd{label: "latest nightly", ref: "nightly"}
+	release, err := nightlySource.Release("nightly")
+	if err != nil {
+		return end, fmt.Errorf("failed to fetch the latest nightly: %w", err)
+	}
+	if commitSource, ok := nightlySource.(utils.CommitSource); ok {
+		if commit, err := commitSource.Commit(release); err == nil && commit != "" {
+			end.ref = commit
+		}
+	}
+	return end, nil
+}
+
+// collectReleaseNotes gathers the notes of the releases after from up to
+// to, newest first
+func collectReleaseNotes(result *changelog, from, to string) error {
+	if semver.Compare("v"+from, "v"+to) > 0 {
+		return fmt.Errorf("%s is newer than %s, swap them", from, to)
+	}
+	tags, err := stableSource.ListTags()
+	if err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if !semver.IsValid(tag.Name) || semver.Prerelease(tag.Name) != "" ||
+			semver.Compare(tag.Name, "v"+from) <= 0 || semver.Compare(tag.Name, "v"+to) > 0 {
+			continue
+		}
+		// Release notes only live on GitHub, mirrors don't carry them
+		notes, err := githubClient.FetchReleaseNotes(tag.Name)
+		if errors.Is(err, utils.ErrNotFound) {
+			notes = utils.ReleaseNotes{TagName: tag.Name}
+		} else if err != nil {
+			return err
+		}
+		if changelogBreaking {
+			notes.Body = strings.Join(breakingLines(notes.Body), "\n")
+			if notes.Body == "" {
+				continue
+			}
+		}
+		result.Releases = append(result.Releases, notes)
+	}
+	return nil
+}
+
+// collectCommits gathers the commits between two builds, newest first
+func collectCommits(result *changelog, from, to changelogEnd) error {
+	comparison, err := githubClient.FetchComparison(from.ref, to.ref)
+	if err != nil {
+		return err
+	}
+	result.TotalCommits = comparison.TotalCommits
+	result.CompareURL = comparison.URL
+	for _, commit := range slices.Backward(comparison.Commits) {
+		if changelogBreaking && !isBreakingCommit(commit.Message) {
+			continue
+		}
+		result.Commits = append(result.Commits, commit)
+	}
+	return nil
+}
+
+var breakingSubject = regexp.MustCompile(`^\w+(\([^)]*\))?!:`)
+
+// isBreakingCommit recognizes conventional commits marked with '!' and those
+// with a BREAKING CHANGE footer
+func isBreakingCommit(message string) bool {
+	return breakingSubject.MatchString(message) || strings.Contains(strings.ToUpper(message), "BREAKING")
+}
+
+// breakingLines keeps the lines of release notes that mention breaking
+// changes, along with whole sections whose heading does
+func breakingLines(notes string) []string {
+	var lines []string
+	inSection := false
+	for _, line := range strings.Split(strings.ReplaceAll(notes, "\r\n", "\n"), "\n") {
+		mentions := strings.Contains(strings.ToLower(line), "breaking")
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			inSection = mentions
+			if inSection {
+				lines = append(lines, line)
+			}
+			continue
+		}
+		if (inSection && strings.TrimSpace(line) != "") || mentions {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func printChangelog(result changelog, commits bool) {
+	if !commits {
+		if len(result.Releases) == 0 {
+			fmt.Printf("Nothing to show between %s and %s.\n", result.From, result.To)
+			return
+		}
+		for i, release := range result.Releases {
+			if i > 0 {
+				fmt.Println()
+			}
+			title := release.TagName
+			if release.Name != "" && release.Name != release.TagName {
+				title += " - " + release.Name
+			}
+			if date, _, found := strings.Cut(release.PublishedAt, "T"); found {
+				title += " (" + date + ")"
+			}
+			color.New(color.Bold).Println(title)
+			if body := strings.TrimSpace(release.Body); body != "" {
+				fmt.Println(body)
+			} else {
+				fmt.Println("No release notes.")
+			}
+			if release.URL != "" {
+				color.HiBlack(release.URL)
+			}
+		}
+		return
+	}
+
+	what := "commits"
+	if changelogBreaking {
+		what = "breaking commits"
+	}
+	color.New(color.Bold).Printf("%d %s from %s to %s\n", len(result.Commits), what, result.From, result.To)
+	for _, commit := range result.Commits {
+		subject, _, _ := strings.Cut(commit.Message, "\n")
+		fmt.Printf("%s %s %s\n", color.YellowString(shortSHA(commit.SHA)), subject, color.HiBlackString("(%s)", commit.Author))
+	}
+	if listed := len(result.Commits); !changelogBreaking && result.TotalCommits > listed {
+		// GitHub lists the oldest ones, the newest are left out
+		fmt.Printf("... %d newer commits aren't listed, see the comparison\n", result.TotalCommits-listed)
+	}
+	if result.CompareURL != "" {
+		color.HiBlack(result.CompareURL)
+	}
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 && !strings.HasPrefix(sha, "v") {
+		return sha[:7]
+	}
+	return sha
+}
@@ -1,7 +1,6 @@
 package commands
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
 	"nvm_manager_go/utils"
@@ -13,10 +12,7 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var (
-	targetDirNightly = filepath.Join(homeDir, ".local", "share", "neoManager", "nightly")
-	versionsFilePath = targetDirNightly + "/versions_info.json"
-)
+var targetDirNightly = filepath.Join(homeDir, ".local", "share", "neoManager", "nightly")
 
 var CleanCmd = &cobra.Command{
 	Use:   "clean",
@@ -28,11 +24,14 @@ var CleanCmd = &cobra.Command{
 		}
 		versionType := args[0]
 		additionalArgs := args[1:]
-		clean(versionType, additionalArgs)
+		if err := clean(versionType, additionalArgs); err != nil {
+			fmt.Println("Error:", err)
+		}
 	},
 }
 
 func clean(target string, options []string) error {
+	target = utils.ExpandAlias(target)
 	switch {
 	case target == "nightly" && len(options) == 0:
 		return cleanLatestNightly()
@@ -61,12 +60,17 @@ func clean(target string, options []string) error {
 		return cleanSpecificStable(target)
 
 	default:
+		if _, ok := utils.FindLinkedVersion(target); ok {
+			return unlinkVersion(target)
+		}
+		if step, _, ok := utils.FindNightlyByCommit(target); ok {
+			return cleanNightlyAt(step)
+		}
 		if strings.HasPrefix(target, "20") {
 			return cleanSpecificNightly(target)
-		} else {
-			// TODO:: add proper error
-			return fmt.Errorf("invalid version: %s", target)
 		}
+		// Ranges, 'latest' and 'previous-stable', or an invalid version
+		return cleanSpecificStable(target)
 	}
 }
 
@@ -97,12 +101,7 @@ func cleanLatestNightly() error {
 	}
 
 	// Update the versions_info.Json file
-	updatedJson, err := json.MarshalIndent(versions, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal updated versions info: %w", err)
-	}
-
-	err = os.WriteFile(versionsFilePath, updatedJson, 0o644)
+	err = utils.WriteVersionsInfo(versions)
 	if err != nil {
 		return fmt.Errorf("failed to write updated versions info: %w", err)
 	}
@@ -126,11 +125,7 @@ func cleanAllNightly() error {
 
 	// Clear and update JSON
 	versions = versions[:0] // Empty the slice
-	updatedJson, err := json.Marshal(versions)
-	if err != nil {
-		return fmt.Errorf("failed to marshal updated versions: %w", err)
-	}
-	err = os.WriteFile(versionsFilePath, updatedJson, 0o644)
+	err = utils.WriteVersionsInfo(versions)
 	if err != nil {
 		return fmt.Errorf("failed to update versions info file: %w", err)
 	}
@@ -145,6 +140,10 @@ func cleanAllStable() error {
 	if err != nil {
 		return fmt.Errorf("failed to delete stable directory: %w", err)
 	}
+	err = utils.RemoveVersionInfo(func(v utils.VersionInfo) bool { return v.Kind == utils.KindStable })
+	if err != nil {
+		return fmt.Errorf("failed to update versions info: %w", err)
+	}
 	fmt.Println("Deleted all stable versions.")
 	return nil
 }
@@ -175,6 +174,15 @@ func cleanSpecificNightly(target string) error { // Target is a date like 2022-1
 		return fmt.Errorf("nightly version %s not found", target)
 	}
 	fmt.Printf("Found version %s at index %d\n", target, index)
+	return cleanNightlyAt(index)
+}
+
+// cleanNightlyAt removes the nightly at the given rollback step
+func cleanNightlyAt(index int) error {
+	versions, err := utils.ReadVersionsInfo()
+	if err != nil {
+		return fmt.Errorf("failed to read versions info: %w", err)
+	}
 
 	// Delete the directory
 	versionDir := versions[index].Directory
@@ -184,10 +192,12 @@ func cleanSpecificNightly(target string) error { // Target is a date like 2022-1
 	}
 
 	versions = append(versions[:index], versions[index+1:]...)
+	for i := range versions {
+		versions[i].UniqueNumber = i
+	}
 
 	// Update versions_info.Json
-	updatedJson, _ := json.Marshal(versions)
-	err = os.WriteFile(versionsFilePath, updatedJson, 0o644)
+	err = utils.WriteVersionsInfo(versions)
 	if err != nil {
 		return err
 	}
@@ -197,8 +207,7 @@ func cleanSpecificNightly(target string) error { // Target is a date like 2022-1
 }
 
 func cleanSpecificStable(versionStr string) error {
-	// If version is stable -> get the version no
-	versionStr, err := utils.ResolveVersion(versionStr)
+	versionStr, err := utils.ResolveInstalledVersion(stableSource, versionStr, notef)
 	if err != nil {
 		return err
 	}
@@ -214,6 +223,13 @@ func cleanSpecificStable(versionStr string) error {
 		return fmt.Errorf("failed to delete stable version %s: %w", versionStr, err)
 	}
 
+	err = utils.RemoveVersionInfo(func(v utils.VersionInfo) bool {
+		return v.Kind == utils.KindStable && v.Version == versionStr
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update versions info: %w", err)
+	}
+
 	fmt.Printf("Deleted stable version %s\n", versionStr)
 	return nil
 }
@@ -0,0 +1,205 @@
+package commands
+
+import (
+	"fmt"
+	"nvm_manager_go/utils"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/mod/semver"
+)
+
+// Completion only reads local state and cached metadata: it runs on every
+// <Tab> and must never wait for the network.
+
+func init() {
+	UseCmd.ValidArgsFunction = completeUse
+	EnvCmd.ValidArgsFunction = completeUse
+	CleanCmd.ValidArgsFunction = completeClean
+	RollbackCmd.ValidArgsFunction = completeRollback
+	InstallCmd.ValidArgsFunction = completeInstall
+	LockCmd.ValidArgsFunction = completeInstall
+	bundleCreateCmd.ValidArgsFunction = completeInstalledVersions
+	ChangelogCmd.ValidArgsFunction = completeChangelog
+	AliasCmd.ValidArgsFunction = completeAlias
+	ExecCmd.ValidArgsFunction = completeExec
+}
+
+// completeUse suggests the versions 'nea use' can switch to
+func completeUse(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	candidates := []string{"nightly\tnewest installed nightly", "stable\tlatest stable release, once installed"}
+	candidates = append(candidates, installedStableCandidates()...)
+	candidates = append(candidates, linkedCandidates()...)
+	candidates = append(candidates, aliasCandidates()...)
+	return candidates, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeExec suggests the version to run, then leaves the arguments of
+// nvim to the shell's file completion
+func completeExec(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	return completeUse(cmd, args, toComplete)
+}
+
+// completeClean suggests what 'nea clean' removes: a channel, a stable
+// version, a nightly by date or a linked version
+func completeClean(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	switch {
+	case len(args) == 1 && (args[0] == "nightly" || args[0] == "stable"):
+		return []string{"all\tevery installed " + args[0]}, cobra.ShellCompDirectiveNoFileComp
+	case len(args) > 0:
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	candidates := []string{
+		"nightly\tthe newest nightly ('nightly all' for every one)",
+		"stable\tthe latest stable release ('stable all' for every one)",
+		"all\tevery nightly and stable",
+	}
+	candidates = append(candidates, installedStableCandidates()...)
+	candidates = append(candidates, nightlyDateCandidates()...)
+	candidates = append(candidates, linkedCandidates()...)
+	candidates = append(candidates, aliasCandidates()...)
+	return candidates, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeRollback suggests the rollback steps with the nightly they go to
+func completeRollback(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	versions, err := utils.ReadVersionsInfo()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	var candidates []string
+	for step := 1; step < len(versions); step++ {
+		candidate := fmt.Sprintf("%d\tnightly %s", step, nightlyDate(versions[step]))
+		if build := versions[step].Build; build != nil && build.Commit != "" {
+			candidate += " (" + build.Commit + ")"
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
+
+// completeInstall suggests the releases known from cached metadata, newest
+// first, leaving out those already on the command line
+func completeInstall(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	candidates := []string{"nightly\tlatest nightly build", "stable\tlatest stable release"}
+	installed, _ := utils.GetLocalStableVersions()
+	for _, version := range cachedRemoteVersions() {
+		description := "stable release"
+		if slices.Contains(installed, version) {
+			description = "installed"
+		}
+		candidates = append(candidates, version+"\t"+description)
+	}
+	candidates = append(candidates, aliasCandidates()...)
+	return withoutArgs(candidates, args), cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
+
+// completeInstalledVersions suggests installed versions, for commands taking
+// several of them
+func completeInstalledVersions(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	candidates := append([]string{"nightly\tnewest installed nightly"}, installedStableCandidates()...)
+	return withoutArgs(candidates, args), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeChangelog suggests the versions 'nea changelog' compares
+func completeChangelog(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 1 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	candidates := []string{"stable\tlatest stable release", "nightly\tnewest installed nightly"}
+	candidates = append(candidates, installedStableCandidates()...)
+	candidates = append(candidates, nightlyDateCandidates()...)
+	candidates = append(candidates, linkedCandidates()...)
+	return candidates, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeAlias suggests the existing aliases, then what they can stand for
+func completeAlias(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	switch len(args) {
+	case 0:
+		return aliasCandidates(), cobra.ShellCompDirectiveNoFileComp
+	case 1:
+		candidates, directive := completeInstall(cmd, nil, toComplete)
+		return append(candidates, "previous-stable\tthe stable release before the latest"), directive
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
+
+func aliasCandidates() []string {
+	aliases, _ := utils.ReadAliases()
+	candidates := make([]string, 0, len(aliases))
+	for name, version := range aliases {
+		candidates = append(candidates, name+"\talias of "+version)
+	}
+	slices.Sort(candidates)
+	return candidates
+}
+
+func installedStableCandidates() []string {
+	versions, _ := utils.GetLocalStableVersions()
+	candidates := make([]string, len(versions))
+	for i, version := range versions {
+		candidates[i] = version + "\tinstalled stable"
+	}
+	return candidates
+}
+
+func nightlyDateCandidates() []string {
+	versions, _ := utils.ReadVersionsInfo()
+	var candidates []string
+	for step, version := range versions {
+		candidate := nightlyDate(version) + "\tnightly"
+		if step > 0 {
+			candidate += fmt.Sprintf(", rollback %d", step)
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates
+}
+
+func linkedCandidates() []string {
+	linked, _ := utils.ReadLinkedVersions()
+	candidates := make([]string, len(linked))
+	for i, entry := range linked {
+		candidates[i] = entry.Name + "\tlinked " + entry.Version
+	}
+	return candidates
+}
+
+// cachedRemoteVersions lists the stable releases in the metadata cache,
+// however old, without going online
+func cachedRemoteVersions() []string {
+	tags, err := cachedStableSource().ListTags()
+	if err != nil {
+		return nil
+	}
+	var versions []string
+	for _, tag := range tags {
+		if semver.IsValid(tag.Name) && semver.Prerelease(tag.Name) == "" {
+			versions = append(versions, strings.TrimPrefix(tag.Name, "v"))
+		}
+	}
+	return versions
+}
+
+// withoutArgs drops the candidates already given as arguments
+func withoutArgs(candidates, args []string) []string {
+	var remaining []string
+	for _, candidate := range candidates {
+		value, _, _ := strings.Cut(candidate, "\t")
+		if !slices.Contains(args, value) {
+			remaining = append(remaining, candidate)
+		}
+	}
+	return remaining
+}
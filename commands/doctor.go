@@ -0,0 +1,319 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"nvm_manager_go/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var DoctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose why nvim isn't the version nea installed",
+	Long: `Check everything that decides which nvim runs: that nea's bin directory
+is on PATH ahead of any other nvim, that the symlink points at a binary
+that runs, that the registry matches what is on disk, that config.json is
+valid, that GitHub is reachable with rate limit left, and that the shell
+is set up. Every problem comes with how to fix it.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		report := &doctorReport{}
+		checkPath(report)
+		checkSymlink(report)
+		checkRegistry(report)
+		checkConfig(report)
+		checkGitHub(report)
+		checkShell(report)
+		report.summary()
+	},
+}
+
+// doctorReport prints the outcome of each check as it runs
+type doctorReport struct {
+	problems int
+	warnings int
+}
+
+func (r *doctorReport) ok(check, format string, args ...any) {
+	fmt.Printf("%s %-10s %s\n", color.GreenString("✓"), check, fmt.Sprintf(format, args...))
+}
+
+func (r *doctorReport) warn(check, message, fix string) {
+	r.warnings++
+	fmt.Printf("%s %-10s %s\n", color.YellowString("!"), check, message)
+	r.printFix(fix)
+}
+
+func (r *doctorReport) fail(check, message, fix string) {
+	r.problems++
+	fmt.Printf("%s %-10s %s\n", color.RedString("✗"), check, message)
+	r.printFix(fix)
+}
+
+func (r *doctorReport) printFix(fix string) {
+	for _, line := range strings.Split(fix, "\n") {
+		if line != "" {
+			fmt.Printf("  %12s %s\n", "", color.CyanString(line))
+		}
+	}
+}
+
+func (r *doctorReport) summary() {
+	fmt.Println()
+	switch {
+	case r.problems > 0:
+		color.Red("%d problem(s) and %d warning(s) found", r.problems, r.warnings)
+	case r.warnings > 0:
+		color.Yellow("No problems, %d warning(s)", r.warnings)
+	default:
+		color.Green("Everything looks fine")
+	}
+}
+
+// checkPath makes sure nvim resolves to nea's symlink
+func checkPath(report *doctorReport) {
+	binDir := utils.BinDir()
+	index := utils.PathIndex(binDir)
+	if index < 0 {
+		report.fail("PATH", binDir+" is not on PATH",
+			fmt.Sprintf("Add this line to your shell configuration and open a new terminal:\nexport PATH=\"%s:$PATH\"", binDir))
+		return
+	}
+
+	found := utils.FindInPath("nvim")
+	if len(found) == 0 || found[0] == utils.SymlinkPath {
+		report.ok("PATH", "%s is on PATH and comes first", binDir)
+		if len(found) > 1 {
+			fmt.Printf("  %12s also found, but shadowed: %s\n", "", strings.Join(found[1:], ", "))
+		}
+		return
+	}
+	report.fail("PATH", fmt.Sprintf("%s comes before nea's nvim on PATH", found[0]),
+		fmt.Sprintf("Put nea's bin directory first in your shell configuration:\nexport PATH=\"%s:$PATH\"\nthen run 'hash -r' (bash/zsh) or open a new terminal, or uninstall %s", binDir, found[0]))
+}
+
+// checkSymlink makes sure bin/nvim points at a binary that runs
+func checkSymlink(report *doctorReport) {
+	target, err := os.Readlink(utils.SymlinkPath)
+	if err != nil {
+		if _, statErr := os.Lstat(utils.SymlinkPath); statErr == nil {
+			report.fail("symlink", utils.SymlinkPath+" is not a symlink", "Remove it and pick a version: rm "+utils.SymlinkPath+" && nea use stable")
+			return
+		}
+		report.fail("symlink", "no version is in use", "Install one and switch to it: nea install stable")
+		return
+	}
+	if _, err = os.Stat(target); err != nil {
+		report.fail("symlink", fmt.Sprintf("%s points at %s, which is gone", utils.SymlinkPath, target),
+			"Switch to an installed version: nea list local, then nea use <version>")
+		return
+	}
+	version, err := utils.NvimVersion(target)
+	if err != nil {
+		report.fail("symlink", fmt.Sprintf("%s doesn't run: %v", target, err),
+			"Reinstall that version, or switch to another one with nea use <version>")
+		return
+	}
+	current, _ := utils.DetermineCurrentVersion()
+	if current == "" {
+		current = target
+	}
+	report.ok("symlink", "using %s (%s)", current, version)
+}
+
+// checkRegistry compares versions_info.json with the directories on disk
+func checkRegistry(report *doctorReport) {
+	entries, err := utils.ReadRegistry()
+	if err != nil {
+		report.fail("registry", err.Error(), "Rebuild it from what is installed: nea repair")
+		return
+	}
+
+	problems := 0
+	registered := make(map[string]bool)
+	for _, entry := range entries {
+		label := registryLabel(entry)
+		registered[filepath.Clean(entry.Directory)] = true
+		if entry.Kind != utils.KindCustom {
+			if _, err := os.Stat(entry.Directory); err != nil {
+				problems++
+				report.fail("registry", fmt.Sprintf("%s is registered but %s is missing", label, entry.Directory), registryFix(entry, false))
+				continue
+			}
+		}
+		if entry.Binary != "" {
+			if _, err := os.Stat(entry.Binary); err != nil {
+				problems++
+				report.fail("registry", fmt.Sprintf("the binary of %s is missing: %s", label, entry.Binary), registryFix(entry, true))
+			}
+		}
+	}
+
+	// Nightlies are always registered; stable versions installed by older
+	// releases of nea aren't, and still work
+	dirs, _ := os.ReadDir(targetNightly)
+	for _, dir := range dirs {
+		path := filepath.Join(targetNightly, dir.Name())
+		if dir.IsDir() && !registered[path] {
+			problems++
+			report.warn("registry", path+" is not registered, rollback and clean don't know about it", "Register it, or remove it if it holds no working build: nea repair")
+		}
+	}
+	if problems == 0 {
+		report.ok("registry", "%d registered version(s), all present on disk", len(entries))
+	} else {
+		report.printFix("Or fix everything above at once: nea repair --dry-run, then nea repair")
+	}
+}
+
+func registryLabel(entry utils.VersionInfo) string {
+	switch {
+	case entry.Kind == utils.KindCustom:
+		return "linked version " + entry.Name
+	case entry.Kind == utils.KindStable:
+		return entry.Version
+	default:
+		return "nightly " + nightlyDate(entry)
+	}
+}
+
+// registryFix tells how to get rid of a broken entry. A stable version
+// whose directory is still there has to be cleaned before reinstalling.
+func registryFix(entry utils.VersionInfo, dirExists bool) string {
+	switch {
+	case entry.Kind == utils.KindCustom:
+		return "Unlink it: nea clean " + entry.Name
+	case entry.Kind == utils.KindStable && dirExists:
+		return fmt.Sprintf("Reinstall it: nea clean %s && nea install %s", entry.Version, entry.Version)
+	case entry.Kind == utils.KindStable:
+		return "Reinstall it: nea install " + entry.Version
+	default:
+		return "Remove it: nea clean " + nightlyDate(entry)
+	}
+}
+
+// checkConfig validates config.json beyond what parsing it requires
+func checkConfig(report *doctorReport) {
+	data, err := os.ReadFile(utils.ConfigPath)
+	if err != nil {
+		report.fail("config", "cannot read config.json: "+err.Error(), "Run any nea command to create a default one")
+		return
+	}
+
+	var config utils.Config
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	problems := 0
+	if err = decoder.Decode(&config); err != nil {
+		if !strings.Contains(err.Error(), "unknown field") {
+			report.fail("config", fmt.Sprintf("%s is not valid: %v", utils.ConfigPath, err), "Fix the JSON, or delete the file to get the defaults back")
+			return
+		}
+		report.warn("config", fmt.Sprintf("%s: %v", utils.ConfigPath, err), "Check the spelling, unknown settings are ignored")
+		json.Unmarshal(data, &config)
+		problems++
+	}
+
+	if config.RollbackLimit < 1 {
+		problems++
+		report.warn("config", fmt.Sprintf("rollbackLimit is %d, the default of %d nightlies is kept", config.RollbackLimit, utils.DefaultRollbackLimit), `Set "rollbackLimit" to how many nightlies to keep, e.g. 7`)
+	}
+	if _, _, err = utils.ReleaseSourcesFromConfig(githubClient); err != nil {
+		problems++
+		report.fail("config", err.Error(), `Use "type": "github" or "mirror" with a "url" in "sources"`)
+	}
+	if _, err = utils.NewHTTPClient(config.Network, ""); err != nil {
+		problems++
+		report.fail("config", err.Error(), `Point "network.caBundle" at a readable PEM file`)
+	}
+	if script := config.HealthCheck.Script; script != "" && !config.HealthCheck.Disabled {
+		if fi, err := os.Stat(utils.ExpandHome(script)); err != nil || fi.Mode()&0o111 == 0 {
+			problems++
+			report.fail("config", "the health check script "+script+" is missing or not executable", "chmod +x "+script+", or remove \"healthCheck.script\"")
+		}
+	}
+	if problems == 0 {
+		report.ok("config", "%s is valid", utils.ConfigPath)
+	}
+}
+
+// checkGitHub checks GitHub answers and there is rate limit left
+func checkGitHub(report *doctorReport) {
+	if githubClient.Offline {
+		report.warn("GitHub", "not checked in offline mode", "")
+		return
+	}
+	limit, err := githubClient.FetchRateLimit()
+	_, stableFromGitHub := stableSource.(*utils.GitHubSource)
+	_, nightlyFromGitHub := nightlySource.(*utils.GitHubSource)
+	if err != nil && !stableFromGitHub && !nightlyFromGitHub {
+		report.warn("GitHub", "GitHub is not reachable, releases come from the configured mirrors: "+err.Error(), "")
+		return
+	}
+	if err != nil {
+		report.fail("GitHub", "GitHub is not reachable: "+err.Error(),
+			"Check your connection; behind a proxy set HTTPS_PROXY and \"network.caBundle\",\nor use a mirror in \"sources\" (see the README)")
+		return
+	}
+	resets := fmt.Sprintf("resets at %s", limit.Reset.Local().Format("15:04:05"))
+	tokenFix := ""
+	if githubClient.Token == "" {
+		tokenFix = "Set GITHUB_TOKEN or GH_TOKEN to raise the limit to 5000 requests an hour"
+	}
+	switch {
+	case limit.Remaining == 0:
+		report.fail("GitHub", fmt.Sprintf("rate limit exhausted, %s (in %s)", resets, time.Until(limit.Reset).Round(time.Second)), tokenFix)
+	case limit.Remaining < 10:
+		report.warn("GitHub", fmt.Sprintf("only %d of %d requests left, %s", limit.Remaining, limit.Limit, resets), tokenFix)
+	default:
+		authenticated := "anonymous"
+		if githubClient.Token != "" {
+			authenticated = "authenticated"
+		}
+		report.ok("GitHub", "reachable, %d of %d requests left (%s)", limit.Remaining, limit.Limit, authenticated)
+	}
+}
+
+// checkShell looks for nea's setup in the configuration of the user's shell
+func checkShell(report *doctorReport) {
+	shell := filepath.Base(os.Getenv("SHELL"))
+	files, known := shellConfigFiles()[shell]
+	if !known {
+		report.warn("shell", fmt.Sprintf("can't check the configuration of shell '%s'", shell), "Make sure it adds "+utils.BinDir()+" to PATH")
+		return
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err == nil && (bytes.Contains(data, []byte("neoManager/bin")) || bytes.Contains(data, []byte("nea init"))) {
+			report.ok("shell", "%s sets up nea", file)
+			return
+		}
+	}
+	line := fmt.Sprintf("eval \"$(nea init %s)\"", shell)
+	if shell == "fish" {
+		line = "nea init fish | source"
+	}
+	message := "nea is not set up in " + strings.Join(files, ", ")
+	fix := fmt.Sprintf("Add this line to %s:\n%s", files[0], line)
+	// It may come from somewhere else, e.g. a system profile
+	if utils.PathIndex(utils.BinDir()) >= 0 {
+		report.warn("shell", message+" (PATH is set somewhere else)", "")
+		return
+	}
+	report.fail("shell", message, fix)
+}
+
+func shellConfigFiles() map[string][]string {
+	return map[string][]string{
+		"bash": {filepath.Join(homeDir, ".bashrc"), filepath.Join(homeDir, ".bash_profile"), filepath.Join(homeDir, ".profile")},
+		"zsh":  {filepath.Join(homeDir, ".zshrc"), filepath.Join(homeDir, ".zprofile"), filepath.Join(homeDir, ".zshenv")},
+		"fish": {filepath.Join(homeDir, ".config", "fish", "config.fish")},
+	}
+}
@@ -0,0 +1,177 @@
+package commands
+
+import (
+	"fmt"
+	"nvm_manager_go/utils"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	envShell string
+	envUnset bool
+)
+
+var EnvCmd = &cobra.Command{
+	Use:   "env [version]",
+	Short: "Print the shell variables that select a version for this shell",
+	Long: `Print, for eval, the variables that make this shell use a version without
+touching the global bin/nvim: NEA_VERSION, NEA_BIN (the version's bin
+directory) and PATH with NEA_BIN in front.
+
+Without a version, the closest .nvim-version file decides; with none, the
+variables are unset and the shell goes back to the global version. This
+is what the hook of 'nea init' runs when changing directories.
+
+  eval "$(nea env 0.10.4)"`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var version string
+		if len(args) == 1 {
+			version = args[0]
+		}
+		if err := printEnv(version); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+	},
+}
+
+func init() {
+	EnvCmd.Flags().StringVar(&envShell, "shell", "", "bash, zsh or fish (default: from $SHELL)")
+	EnvCmd.Flags().BoolVar(&envUnset, "unset", false, "print the commands that go back to the global version")
+}
+
+func printEnv(version string) error {
+	shell, err := shellName(envShell)
+	if err != nil {
+		return err
+	}
+
+	var versionFile string
+	if version == "" && !envUnset {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		if versionFile, err = utils.FindVersionFile(cwd); err == nil {
+			if version, err = utils.ReadVersionFile(versionFile); err != nil {
+				return err
+			}
+		}
+	}
+	if version == "" {
+		// Nothing to undo keeps PATH untouched
+		if os.Getenv("NEA_VERSION") == "" && !envUnset {
+			return nil
+		}
+		fmt.Print(envUnsetScript(shell))
+		return nil
+	}
+
+	binary, err := installedBinary(version)
+	if err != nil {
+		// Stay on the global version rather than a stale one
+		fmt.Print(envUnsetScript(shell))
+		if versionFile != "" {
+			return fmt.Errorf("%s asks for %s: %w", versionFile, version, err)
+		}
+		return err
+	}
+	binDir := filepath.Dir(binary)
+	if os.Getenv("NEA_VERSION") == version && os.Getenv("NEA_BIN") == binDir {
+		return nil
+	}
+	fmt.Print(envScript(shell, version, binDir))
+	return nil
+}
+
+// installedBinary returns the binary of an installed version without going
+// online, keywords resolve against the cached releases. It runs from shell
+// hooks, so a version standing in for a keyword goes without a notice.
+func installedBinary(version string) (string, error) {
+	version = utils.ExpandAlias(version)
+	if linked, ok := utils.FindLinkedVersion(version); ok {
+		return linked.Binary, nil
+	}
+	if _, nightly, ok := utils.FindNightlyByCommit(version); ok {
+		return nightlyBinary(nightly)
+	}
+	if version == "nightly" {
+		versions, err := utils.ReadVersionsInfo()
+		if err != nil || len(versions) == 0 {
+			return "", fmt.Errorf("no nightly versions installed, run 'nea install nightly'")
+		}
+		return nightlyBinary(versions[0])
+	}
+	version, err := utils.ResolveInstalledVersion(cachedStableSource(), version, nil)
+	if err != nil {
+		return "", err
+	}
+	binary, err := stableBinary(version)
+	if err != nil {
+		return "", fmt.Errorf("%w, run 'nea install %s'", err, version)
+	}
+	return binary, nil
+}
+
+// envPath returns PATH without the previous NEA_BIN and with binDir in front
+func envPath(binDir string) []string {
+	previous := os.Getenv("NEA_BIN")
+	var paths []string
+	if binDir != "" {
+		paths = append(paths, binDir)
+	}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir != previous && dir != binDir && !slices.Contains(paths, dir) {
+			paths = append(paths, dir)
+		}
+	}
+	return paths
+}
+
+func envScript(shell, version, binDir string) string {
+	paths := envPath(binDir)
+	if shell == "fish" {
+		return fmt.Sprintf("set -gx NEA_VERSION %s\nset -gx NEA_BIN %s\nset -gx PATH %s\n",
+			fishQuote(version), fishQuote(binDir), fishQuoteAll(paths))
+	}
+	return fmt.Sprintf("export NEA_VERSION=%s\nexport NEA_BIN=%s\nexport PATH=%s\n",
+		utils.ShellQuote(version), utils.ShellQuote(binDir), utils.ShellQuote(strings.Join(paths, string(filepath.ListSeparator))))
+}
+
+func envUnsetScript(shell string) string {
+	paths := envPath("")
+	if shell == "fish" {
+		return fmt.Sprintf("set -e NEA_VERSION\nset -e NEA_BIN\nset -gx PATH %s\n", fishQuoteAll(paths))
+	}
+	return fmt.Sprintf("unset NEA_VERSION NEA_BIN\nexport PATH=%s\n", utils.ShellQuote(strings.Join(paths, string(filepath.ListSeparator))))
+}
+
+// shellName validates a shell name, taking it from $SHELL when empty
+func shellName(shell string) (string, error) {
+	if shell == "" {
+		shell = filepath.Base(os.Getenv("SHELL"))
+	}
+	switch shell {
+	case "bash", "zsh", "fish":
+		return shell, nil
+	}
+	return "", fmt.Errorf("unsupported shell '%s', expected bash, zsh or fish", shell)
+}
+
+// fishQuote single-quotes a value for fish, which escapes inside quotes
+func fishQuote(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(value) + "'"
+}
+
+func fishQuoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = fishQuote(value)
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	"github.com/spf13/cobra"
+)
+
+var ExecCmd = &cobra.Command{
+	Use:   "exec <version> [args...]",
+	Short: "Run a version of Neovim without switching to it",
+	Long: `Run an installed version with the given arguments, leaving the version in
+use alone. The version is anything 'nea use' accepts: a stable version, a
+range, an alias, 'nightly', a nightly's commit or a linked version.
+
+Everything after the version goes to nvim, '--' may separate them.
+
+  nea exec 0.9.5 --clean
+  nea exec nightly -- -u NONE init.lua`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := execVersion(args[0], args[1:]); err != nil {
+			fmt.Println("Error:", err)
+		}
+	},
+}
+
+func init() {
+	// nvim's own flags follow the version
+	ExecCmd.Flags().SetInterspersed(false)
+}
+
+// execVersion replaces nea with the binary of version
+func execVersion(version string, args []string) error {
+	binary, err := installedBinary(version)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if err = syscall.Exec(binary, append([]string{binary}, args...), os.Environ()); err != nil {
+		return fmt.Errorf("failed to run %s: %w", binary, err)
+	}
+	return nil
+}
@@ -2,36 +2,119 @@ package commands
 
 import (
 	"fmt"
+	"nvm_manager_go/utils"
 	"os"
-	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
 )
 
-// printDirContents prints the directory structure for debugging purposes
-func printDirContents(dir string) error {
-	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+// githubClient is used by every command that talks to the GitHub API
+var githubClient = utils.NewGitHubClient("")
 
-		rel, err := filepath.Rel(dir, path)
-		if err != nil {
-			rel = path
-		}
+// Where stable and nightly releases come from, GitHub unless configured otherwise
+var (
+	stableSource  utils.ReleaseSource = &utils.GitHubSource{Client: githubClient}
+	nightlySource utils.ReleaseSource = &utils.GitHubSource{Client: githubClient}
+)
+
+// SetGitHubClient injects the GitHub API client the commands use
+func SetGitHubClient(client *utils.GitHubClient) {
+	githubClient = client
+}
+
+// SetReleaseSources injects the release source of each channel
+func SetReleaseSources(stable, nightly utils.ReleaseSource) {
+	stableSource = stable
+	nightlySource = nightly
+}
+
+// quietOutput is set by 'update --quiet': the install path then leaves out
+// its progress and timestamps its warnings for the log
+var quietOutput bool
+
+// logPrefix starts the lines of quiet output with a timestamp
+func logPrefix() string {
+	if !quietOutput {
+		return ""
+	}
+	return time.Now().Format("2006-01-02 15:04:05") + " "
+}
 
-		if rel == "." {
-			return nil
+// progressf reports what the install path is doing, unless quiet
+func progressf(format string, a ...any) {
+	if !quietOutput {
+		fmt.Printf(format+"\n", a...)
+	}
+}
+
+// warnf reports a problem that doesn't stop the install
+func warnf(format string, a ...any) {
+	fmt.Printf(logPrefix()+format+"\n", a...)
+}
+
+// notef tells which version stands in for the one asked for
+func notef(message string) {
+	fmt.Println(color.YellowString("Note: %s", message))
+}
+
+// cachedStableSource is the configured stable source with its metadata
+// served from the cache, however old, for output that can't wait on the
+// network. githubClient is shared with running installs and stays as is.
+func cachedStableSource() utils.ReleaseSource {
+	client := githubClient.CacheOnly()
+	source, _, err := utils.ReleaseSourcesFromConfig(client)
+	if err != nil {
+		return &utils.GitHubSource{Client: client}
+	}
+	return source
+}
+
+// download fetches url into filePath through the artifact cache. A cached
+// copy must match checksum when one is known. Without one it is only reused
+// when reuse is set, since the file behind some URLs (the nightly's) changes,
+// or when offline as there is nothing better.
+func download(url, checksum, filePath string, reuse bool) error {
+	if reuse || checksum != "" || githubClient.Offline {
+		if artifact, ok := utils.LookupArtifact(url, checksum); ok {
+			progressf("Using cached %s", artifact.Name)
+			return utils.RestoreArtifact(artifact, filePath)
 		}
+	}
+	if githubClient.Offline {
+		return fmt.Errorf("%w: cannot download %s", utils.ErrOffline, url)
+	}
 
-		type_indicator := " "
-		if info.IsDir() {
-			type_indicator = "/"
-		} else if info.Mode()&os.ModeSymlink != 0 {
-			type_indicator = "@"
-		} else if info.Mode()&0111 != 0 {
-			type_indicator = "*"
+	if err := utils.DownloadArchive(url, filePath); err != nil {
+		return err
+	}
+	if checksum != "" {
+		if err := utils.VerifySHA256(filePath, checksum); err != nil {
+			os.Remove(filePath)
+			return err
 		}
+	}
+	if _, err := utils.StoreArtifact(url, filePath); err != nil {
+		warnf("Warning: failed to cache the download: %v", err)
+	}
+	return nil
+}
+
+// promptMu keeps parallel installs from asking questions over each other
+var promptMu sync.Mutex
 
-		fmt.Printf("%s%s\n", rel, type_indicator)
-		return nil
-	})
+// confirm asks a yes/no question on the terminal, defaulting to no
+func confirm(question string) bool {
+	if !utils.IsTerminal(os.Stdin) {
+		return false
+	}
+	promptMu.Lock()
+	defer promptMu.Unlock()
+	fmt.Printf("%s [y/N] ", question)
+	var answer string
+	fmt.Scanln(&answer)
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
 }
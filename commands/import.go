@@ -0,0 +1,284 @@
+package commands
+
+import (
+	"fmt"
+	"nvm_manager_go/utils"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	importFrom string
+	importMove bool
+)
+
+var ImportCmd = &cobra.Command{
+	Use:   "import [path...]",
+	Short: "Import Neovim installations from other version managers",
+	Long: `Import existing Neovim installations into nea.
+
+Sources:
+  --from bob  - Versions installed by bob (defaults to ~/.local/share/bob)
+  --from dir  - Manual installations, e.g. 'nea import --from dir /opt/nvim-*'
+                Each path can be an installation or a directory containing several.
+
+Stable versions are placed under stable/ and dev builds under nightly/.
+Installations are copied unless --move is given.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		utils.Setup()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := importVersions(importFrom, args, importMove); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+	},
+}
+
+func init() {
+	ImportCmd.Flags().StringVar(&importFrom, "from", "dir", "source to import from: bob or dir")
+	ImportCmd.Flags().BoolVar(&importMove, "move", false, "move installations instead of copying them")
+}
+
+// importCandidate is an installation found on disk, root being the prefix
+// that contains bin/nvim
+type importCandidate struct {
+	root   string
+	binary string
+}
+
+type skippedImport struct {
+	path   string
+	reason string
+}
+
+var stableVersionRegex = regexp.MustCompile(`^v?([0-9]+\.[0-9]+\.[0-9]+)$`)
+
+func importVersions(from string, paths []string, move bool) error {
+	switch from {
+	case "bob":
+		if len(paths) == 0 {
+			paths = []string{defaultBobDir()}
+		}
+	case "dir":
+		if len(paths) == 0 {
+			return fmt.Errorf("you must specify at least one directory to import from")
+		}
+	default:
+		return fmt.Errorf("unknown source '%s', expected 'bob' or 'dir'", from)
+	}
+
+	candidates, skipped := discoverInstallations(paths)
+	imported := 0
+	for _, candidate := range candidates {
+		version, err := importInstallation(candidate, move)
+		if err != nil {
+			skipped = append(skipped, skippedImport{candidate.root, err.Error()})
+			continue
+		}
+		color.Green("Imported %s from %s", version, candidate.root)
+		imported++
+	}
+
+	fmt.Printf("\nImported %d version(s)\n", imported)
+	if len(skipped) > 0 {
+		color.Yellow("Skipped %d path(s):", len(skipped))
+		for _, s := range skipped {
+			fmt.Printf("  %s: %s\n", s.path, s.reason)
+		}
+	}
+	return nil
+}
+
+func defaultBobDir() string {
+	if dataHome := os.Getenv("XDG_DATA_HOME"); dataHome != "" {
+		return filepath.Join(dataHome, "bob")
+	}
+	return filepath.Join(homeDir, ".local", "share", "bob")
+}
+
+// discoverInstallations treats each path as an installation, or failing that
+// as a directory holding one installation per entry (bob's layout)
+func discoverInstallations(paths []string) ([]importCandidate, []skippedImport) {
+	var candidates []importCandidate
+	var skipped []skippedImport
+
+	for _, path := range paths {
+		if root, binary, ok := findInstallRoot(path); ok {
+			candidates = append(candidates, importCandidate{root, binary})
+			continue
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			skipped = append(skipped, skippedImport{path, err.Error()})
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			dir := filepath.Join(path, entry.Name())
+			if root, binary, ok := findInstallRoot(dir); ok {
+				candidates = append(candidates, importCandidate{root, binary})
+			} else {
+				skipped = append(skipped, skippedImport{dir, "no nvim binary found"})
+			}
+		}
+	}
+	return candidates, skipped
+}
+
+// findInstallRoot looks for bin/nvim in dir, or in its only subdirectory,
+// which covers both flat installs and extracted release archives. A
+// symlinked dir, such as Homebrew's opt/neovim, is followed so the files
+// it points at are imported rather than the link.
+func findInstallRoot(dir string) (string, string, bool) {
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	binary := filepath.Join(dir, "bin", "nvim")
+	if fi, err := os.Stat(binary); err == nil && !fi.IsDir() {
+		return dir, binary, true
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", "", false
+	}
+	var subdirs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			subdirs = append(subdirs, entry.Name())
+		}
+	}
+	// Several subdirectories means a directory of installations, not one
+	if len(subdirs) != 1 {
+		return "", "", false
+	}
+
+	root := filepath.Join(dir, subdirs[0])
+	binary = filepath.Join(root, "bin", "nvim")
+	if fi, err := os.Stat(binary); err == nil && !fi.IsDir() {
+		return root, binary, true
+	}
+	return "", "", false
+}
+
+func importInstallation(candidate importCandidate, move bool) (string, error) {
+	version, err := utils.NvimVersion(candidate.binary)
+	if err != nil {
+		return "", err
+	}
+
+	transfer := utils.CopyDir
+	if move {
+		transfer = utils.MoveDir
+	}
+
+	if match := stableVersionRegex.FindStringSubmatch(version); match != nil {
+		return importStable(candidate, match[1], transfer)
+	}
+	if strings.Contains(version, "-dev") {
+		buildDate, err := nightlyBuildDate(candidate.binary, version)
+		if err != nil {
+			return "", err
+		}
+		return importNightly(candidate, version, buildDate, transfer)
+	}
+	return "", fmt.Errorf("unrecognized version %s", version)
+}
+
+func importStable(candidate importCandidate, version string, transfer func(src, dst string) error) (string, error) {
+	targetDir := filepath.Join(targetDirStable, version)
+	if _, err := os.Stat(targetDir); err == nil {
+		return "", fmt.Errorf("version %s is already installed", version)
+	}
+
+	if err := os.MkdirAll(targetDir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create target directory: %w", err)
+	}
+	destRoot := filepath.Join(targetDir, "nvim")
+	if err := transfer(candidate.root, destRoot); err != nil {
+		os.RemoveAll(targetDir)
+		return "", fmt.Errorf("failed to import files: %w", err)
+	}
+
+	err := utils.RegisterStableVersion(utils.VersionInfo{
+		Version:   version,
+		Directory: targetDir,
+		RootDir:   "nvim",
+		Binary:    filepath.Join(destRoot, "bin", "nvim"),
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		Build:     buildInfo(filepath.Join(destRoot, "bin", "nvim")),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to update versions info: %w", err)
+	}
+	return version, nil
+}
+
+// nightlyBuildDate dates a development build by the commit its version
+// names, nvim --version doesn't print a build date. The binary's mtime is
+// only used when GitHub can't tell.
+func nightlyBuildDate(binary, version string) (string, error) {
+	commit := utils.CommitFromVersion(version)
+	if commit != "" {
+		date, err := githubClient.FetchCommitDate(commit)
+		if err == nil {
+			return date.UTC().Format(time.RFC3339), nil
+		}
+		color.Yellow("Could not date %s from its commit, using the binary's modification time: %v", version, err)
+	}
+	fi, err := os.Stat(binary)
+	if err != nil {
+		return "", err
+	}
+	return fi.ModTime().UTC().Format(time.RFC3339), nil
+}
+
+func importNightly(candidate importCandidate, version, buildDate string, transfer func(src, dst string) error) (string, error) {
+	versions, err := utils.ReadVersionsInfo()
+	if err != nil {
+		return "", err
+	}
+	for _, v := range versions {
+		if v.Version == version {
+			return "", fmt.Errorf("nightly %s is already installed", version)
+		}
+	}
+
+	targetDir, err := utils.CreateTargetDirectory(buildDate)
+	if err != nil {
+		return "", fmt.Errorf("failed to create target directory: %w", err)
+	}
+	destRoot := filepath.Join(targetDir, "nvim")
+	if err = transfer(candidate.root, destRoot); err != nil {
+		os.RemoveAll(destRoot)
+		os.Remove(targetDir) // only succeeds if nothing else lives there
+		return "", fmt.Errorf("failed to import files: %w", err)
+	}
+
+	// Imported builds have no release node_id, the dev version is unique per
+	// commit. They count towards the rollback limit like any nightly.
+	release := utils.ReleaseInfo{NodeID: "imported:" + version, CreatedAt: buildDate}
+	registryMu.Lock()
+	err = updateVersionsInfo(release, utils.VersionInfo{
+		Version:   version,
+		Directory: targetDir,
+		RootDir:   "nvim",
+		Binary:    filepath.Join(destRoot, "bin", "nvim"),
+		Build:     buildInfo(filepath.Join(destRoot, "bin", "nvim")),
+	})
+	registryMu.Unlock()
+	if err != nil {
+		return "", fmt.Errorf("failed to update versions info: %w", err)
+	}
+	return "nightly " + version, nil
+}
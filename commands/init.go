@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"fmt"
+	"nvm_manager_go/utils"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var InitCmd = &cobra.Command{
+	Use:   "init [bash|zsh|fish]",
+	Short: "Print the shell integration to add to your shell configuration",
+	Long: `Print a snippet that puts nea's bin directory on PATH, loads completion
+and switches version when changing directories: in a directory with a
+.nvim-version file (or below one), nvim is the version it names, for this
+shell only (see 'nea env'). Add to your shell configuration:
+
+  bash:  eval "$(nea init bash)"      in ~/.bashrc
+  zsh:   eval "$(nea init zsh)"       in ~/.zshrc
+  fish:  nea init fish | source       in ~/.config/fish/config.fish
+
+The shell defaults to $SHELL.`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"bash", "zsh", "fish"},
+	Run: func(cmd *cobra.Command, args []string) {
+		var shell string
+		if len(args) == 1 {
+			shell = args[0]
+		}
+		if err := printInit(shell); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+	},
+}
+
+func printInit(shell string) error {
+	shell, err := shellName(shell)
+	if err != nil {
+		return err
+	}
+	// The snippet runs nea before PATH may have it, so it uses its full path
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the nea binary: %w", err)
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return fmt.Errorf("failed to locate the nea binary: %w", err)
+	}
+
+	replacer := strings.NewReplacer("{bin}", utils.ShellQuote(utils.BinDir()), "{nea}", utils.ShellQuote(exe))
+	switch shell {
+	case "bash":
+		fmt.Print(replacer.Replace(bashInit))
+	case "zsh":
+		fmt.Print(replacer.Replace(zshInit))
+	case "fish":
+		replacer = strings.NewReplacer("{bin}", fishQuote(utils.BinDir()), "{nea}", fishQuote(exe))
+		fmt.Print(replacer.Replace(fishInit))
+	}
+	return nil
+}
+
+const bashInit = `# nea shell integration for bash
+case ":$PATH:" in
+  *:{bin}:*) ;;
+  *) export PATH={bin}:"$PATH" ;;
+esac
+
+source <({nea} completion bash)
+
+__nea_hook() {
+  if [ "$PWD" != "${__NEA_PWD-}" ]; then
+    __NEA_PWD=$PWD
+    eval "$({nea} env --shell bash)"
+  fi
+}
+case ";${PROMPT_COMMAND-};" in
+  *";__nea_hook;"*) ;;
+  *) PROMPT_COMMAND="__nea_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}" ;;
+esac
+`
+
+const zshInit = `# nea shell integration for zsh
+case ":$PATH:" in
+  *:{bin}:*) ;;
+  *) export PATH={bin}:"$PATH" ;;
+esac
+
+if (( ! $+functions[compdef] )); then
+  autoload -Uz compinit && compinit
+fi
+source <({nea} completion zsh)
+
+__nea_hook() {
+  eval "$({nea} env --shell zsh)"
+}
+autoload -Uz add-zsh-hook
+add-zsh-hook chpwd __nea_hook
+__nea_hook
+`
+
+const fishInit = `# nea shell integration for fish
+if not contains -- {bin} $PATH
+    set -gx PATH {bin} $PATH
+end
+
+{nea} completion fish | source
+
+function __nea_hook --on-variable PWD
+    {nea} env --shell fish | source
+end
+__nea_hook
+`
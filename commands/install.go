@@ -6,132 +6,421 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 )
 
 var (
 	homeDir       = os.Getenv("HOME")
 	appDir        = filepath.Join(homeDir, ".local", "share", "neoManager")
-	neovimURL     = "https://github.com/neovim/neovim/releases/download/nightly/nvim-macos.tar.gz"
 	targetNightly = filepath.Join(homeDir, ".local", "share", "neoManager", "nightly")
 	// stableBaseURL   = "https://github.com/neovim/neovim/releases/download/v"
 	targetDirStable = filepath.Join(homeDir, ".local", "share", "neoManager", "stable/")
-	tagsURL         = "https://api.github.com/repos/neovim/neovim/tags"
 	versionFilePath = filepath.Join(targetNightly, "versions_info.json")
-	nvm_night_url   = "https://github.com/neovim/neovim/releases/download/nightly/"
 )
 
+var (
+	installAppImage   bool
+	installFromSource bool
+	installFromFile   string
+	installJobs       int
+	installDefault    string
+	installIsLocked   bool
+	installSkipCheck  bool
+)
+
+// registryMu serializes registry updates of installs running in parallel
+var registryMu sync.Mutex
+
 var InstallCmd = &cobra.Command{
-	Use:   "install",
-	Short: "Install a Neovim version",
-	Long: `Install a Neovim version. Valid formats:
+	Use:   "install <versions...>",
+	Short: "Install Neovim versions",
+	Long: `Install one or more Neovim versions. Valid formats:
 - nightly: Latest nightly build
 - stable: Latest stable version
-- x.y.z: Specific version (e.g., 0.9.5)`,
-	Args: cobra.ExactArgs(1),
+- x.y.z: Specific version (e.g., 0.9.5)
+
+With a single version, nea switches to it once installed. Several versions
+(e.g. 'nea install 0.9.5 0.10.4 nightly', or --from-file with one version
+per line) are downloaded and extracted in parallel, and nea only switches
+to the one given with --default.
+
+On Linux, nightly builds are installed from the release tarball. Use
+--appimage to install the AppImage instead; it is extracted so FUSE
+isn't required.
+
+When no prebuilt binary can run on this system (e.g. musl/Alpine or an
+old glibc), nea offers to build from source; --from-source forces it.
+
+With --locked, the builds pinned in the closest nea.lock are installed
+(all of them, or the given versions) and its default is switched to.
+
+Every new build is smoke-tested before it is kept: 'nvim --version' and
+'nvim --headless --clean +qa' must succeed, and so must the script set as
+healthCheck.script in config.json. A build failing the check is removed
+and the version in use stays active. --skip-check keeps it anyway.`,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		utils.Setup()
 	},
 	Run: func(cmd *cobra.Command, args []string) {
-		version := args[0]
-
-		var err error
-		if version == "nightly" {
-			err = installNightly()
-			if err != nil {
-				fmt.Println("Failed to install nightly:", err)
-				return
+		if installIsLocked {
+			if err := installLocked(args); err != nil {
+				fmt.Println("Error:", err)
 			}
-		} else {
-			// Validate and resolve version before proceeding
-			resolvedVersion, err := utils.ResolveVersion(version)
+			return
+		}
+
+		versions := args
+		if installFromFile != "" {
+			fromFile, err := readVersionList(installFromFile)
 			if err != nil {
-				fmt.Printf("Error: %v\n", err)
+				fmt.Println("Error:", err)
 				return
 			}
+			versions = append(versions, fromFile...)
+		}
 
-			err = InstallSpecificStable(resolvedVersion)
-			if err != nil {
-				fmt.Printf("Failed to install version %s: %v\n", resolvedVersion, err)
-				return
+		switch {
+		case len(versions) == 0:
+			fmt.Println("Error: You must specify at least one version")
+		case len(versions) == 1 && installDefault == "":
+			installOne(versions[0])
+		default:
+			if err := installMany(versions, installJobs, installDefault); err != nil {
+				fmt.Println("Error:", err)
 			}
 		}
 	},
 }
 
+func init() {
+	InstallCmd.Flags().BoolVar(&installAppImage, "appimage", false, "install the nightly AppImage instead of the tarball (Linux only)")
+	InstallCmd.Flags().BoolVar(&installFromSource, "from-source", false, "build Neovim from source instead of using a prebuilt binary")
+	InstallCmd.Flags().StringVar(&installFromFile, "from-file", "", "read the versions to install from a file, one per line")
+	InstallCmd.Flags().IntVarP(&installJobs, "jobs", "j", 4, "how many versions to install at the same time")
+	InstallCmd.Flags().StringVar(&installDefault, "default", "", "version to switch to once everything is installed")
+	InstallCmd.Flags().BoolVar(&installIsLocked, "locked", false, "install exactly the builds pinned in nea.lock")
+	InstallCmd.Flags().BoolVar(&installSkipCheck, "skip-check", false, "don't smoke-test new builds before keeping them")
+}
+
+// installOne installs a single version and switches to it
+func installOne(version string) {
+	version = utils.ExpandAlias(version)
+	if version == "nightly" {
+		if err := installNightly(installAppImage); err != nil {
+			fmt.Println("Failed to install nightly:", err)
+		}
+		return
+	}
+
+	// Validate and resolve version before proceeding
+	resolvedVersion, err := utils.ResolveVersion(stableSource, version)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	if err = InstallSpecificStable(resolvedVersion); err != nil {
+		fmt.Printf("Failed to install version %s: %v\n", resolvedVersion, err)
+	}
+}
+
 func InstallSpecificStable(version string) error {
 	startTime := time.Now()
 	defer func() { fmt.Printf("Total execution time: %v\n", time.Since(startTime)) }()
 
 	// if version is stable -> get the version no
-	version, err := utils.ResolveVersion(version)
+	version, err := utils.ResolveVersion(stableSource, version)
 	if err != nil {
 		return err
 	}
 
+	installed, err := installStable(version)
+	if err != nil || !installed {
+		return err
+	}
+
+	err = useVersion(version, nil)
+	if err != nil {
+		return fmt.Errorf("failed to switch to version %s: %w", version, err)
+	}
+	green := color.New(color.FgCyan).PrintfFunc()
+	green("Neovim version %s installed successfully!\n", version)
+	return nil
+}
+
+// installStable installs a resolved stable version without switching to
+// it. It returns false when the version was already installed.
+func installStable(version string) (bool, error) {
 	targetDir := filepath.Join(targetDirStable, version)
-	_, err = os.Stat(targetDir)
+	_, err := os.Stat(targetDir)
 	if err == nil || !os.IsNotExist(err) {
-		fmt.Println("Version", version, "is already installed.")
-		return nil
+		progressf("Version %s is already installed.", version)
+		return false, nil
 	}
 
-	// Determine the correct archive filename based on the version
-	archiveFilename, err := getArchiveFilename(version)
-	if err != nil {
-		return fmt.Errorf("failed to determine archive filename: %w", err)
+	// Pick the right asset for this platform from the release's asset list,
+	// building from source when there is none that can run here
+	var release utils.ReleaseInfo
+	var asset utils.ReleaseAsset
+	fromSource := installFromSource
+	if !fromSource {
+		release, err = stableSource.Release("v" + version)
+		if err != nil {
+			return false, err
+		}
+		asset, err = utils.ResolveCompatibleAsset(release.Assets, utils.CurrentPlatform(), utils.FormatTarball, version)
+		if err != nil {
+			if !shouldBuildFromSource(err) {
+				return false, fmt.Errorf("failed to find a build of %s: %w", version, err)
+			}
+			fromSource = true
+		}
 	}
 
-	stableURL := utils.StableBaseURL + version + "/" + archiveFilename
+	return installStableRelease(stableSource, version, release, asset, fromSource)
+}
+
+// installStableRelease installs the given asset of a stable release, or
+// builds it, and registers it
+func installStableRelease(source utils.ReleaseSource, version string, release utils.ReleaseInfo, asset utils.ReleaseAsset, fromSource bool) (bool, error) {
+	targetDir := filepath.Join(targetDirStable, version)
 
 	// 1. Create the target directory
-	if err = os.MkdirAll(targetDir, 0755); err != nil {
-		return fmt.Errorf("failed to create target directory: %w", err)
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return false, fmt.Errorf("failed to create target directory: %w", err)
 	}
 
-	// 2. Download the Neovim archive
-	archivePath := filepath.Join(targetDir, archiveFilename)
-	if err = utils.DownloadArchive(stableURL, archivePath); err != nil {
-		return fmt.Errorf("failed to download Neovim: %w", err)
+	// 2. Download and extract the archive, or build it
+	var rootDir string
+	var err error
+	if fromSource {
+		rootDir, err = buildFromSource("v"+version, targetDir)
+		asset.Name = "source"
+	} else {
+		rootDir, err = installAsset(source, release, asset, targetDir)
+	}
+	binary := filepath.Join(targetDir, rootDir, "bin", "nvim")
+	if err == nil {
+		err = checkBuild(version, binary)
+	}
+	if err != nil {
+		// Don't leave a half installed version behind, it would look installed
+		os.RemoveAll(targetDir)
+		return false, err
+	}
+
+	// 3. Record where it was installed so use never has to guess
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	err = utils.RegisterStableVersion(utils.VersionInfo{
+		Version:   version,
+		Directory: targetDir,
+		RootDir:   rootDir,
+		Binary:    binary,
+		Asset:     asset.Name,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		Build:     buildInfo(binary),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to update versions info: %w", err)
+	}
+	return true, nil
+}
+
+// buildInfo records what 'nvim --version' says about a new build, nothing
+// when it can't tell
+func buildInfo(binary string) *utils.BuildInfo {
+	info, err := utils.ReadBuildInfo(binary)
+	if err != nil {
+		return nil
 	}
+	return &info
+}
 
-	// 3. Extract the archive or set executable permissions for AppImage
-	if strings.HasSuffix(archiveFilename, ".tar.gz") {
-		// Extract tar.gz archive
-		err = utils.ExtractTarGz(archivePath, targetDir)
+// checkBuild smoke-tests a new build before it is registered, so a broken
+// one is never switched to
+func checkBuild(version, binary string) error {
+	if installSkipCheck {
+		return nil
+	}
+	if err := utils.CheckBuild(binary); err != nil {
+		return fmt.Errorf("the %s build failed its health check, keeping the current version: %w", version, err)
+	}
+	return nil
+}
+
+// installResult is the outcome of one version of a parallel install
+type installResult struct {
+	version   string
+	installed bool
+	err       error
+	elapsed   time.Duration
+}
+
+// installMany installs several versions at once, at most jobs at a time,
+// and switches to defaultVersion when it is given
+func installMany(versions []string, jobs int, defaultVersion string) error {
+	startTime := time.Now()
+
+	// Resolve everything up front so "stable" and "0.10.4" aren't installed twice
+	var resolved []string
+	seen := make(map[string]bool)
+	for _, version := range versions {
+		version = utils.ExpandAlias(version)
+		if version != "nightly" {
+			resolvedVersion, err := utils.ResolveVersion(stableSource, version)
+			if err != nil {
+				return err
+			}
+			version = resolvedVersion
+		}
+		if !seen[version] {
+			seen[version] = true
+			resolved = append(resolved, version)
+		}
+	}
+	defaultVersion = utils.ExpandAlias(defaultVersion)
+	if defaultVersion != "" && defaultVersion != "nightly" {
+		resolvedDefault, err := utils.ResolveVersion(stableSource, defaultVersion)
 		if err != nil {
-			return fmt.Errorf("failed to extract Neovim: %w", err)
+			return fmt.Errorf("invalid default version: %w", err)
 		}
+		defaultVersion = resolvedDefault
+	}
 
-		// 4. Remove the downloaded archive
-		err = os.Remove(archivePath)
-		if err != nil {
-			fmt.Println("Warning (non-fatal): Failed to remove Neovim archive:", err)
+	results := runInstalls(resolved, jobs)
+
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetHeader([]string{"Version", "Result", "Time"})
+	failed := 0
+	defaultOK := false
+	for _, result := range results {
+		status := "installed"
+		switch {
+		case result.err != nil:
+			status = "failed: " + result.err.Error()
+			failed++
+		case !result.installed:
+			status = "already installed"
 		}
-	} else if strings.HasSuffix(archiveFilename, ".appimage") {
-		// Set executable permissions for AppImage
-		err = os.Chmod(archivePath, 0755)
-		if err != nil {
-			return fmt.Errorf("failed to set executable permission: %w", err)
+		if result.err == nil && result.version == defaultVersion {
+			defaultOK = true
 		}
+		table.Append([]string{result.version, status, result.elapsed.String()})
+	}
+	table.Render()
+	fmt.Print(tableString.String())
+	fmt.Printf("Total execution time: %v\n", time.Since(startTime))
 
-		// Move the AppImage to the bin directory
-		binDir := filepath.Join(appDir, "bin")
-		finalPath := filepath.Join(binDir, "nvim") // rename to nvim
-		err = os.Rename(archivePath, finalPath)
-		if err != nil {
-			return fmt.Errorf("failed to move AppImage to bin directory: %w", err)
+	if defaultVersion != "" {
+		switch {
+		case !seen[defaultVersion]:
+			fmt.Printf("Not switching to %s, it wasn't part of this install.\n", defaultVersion)
+		case !defaultOK:
+			fmt.Printf("Not switching to %s, its install failed.\n", defaultVersion)
+		default:
+			if err := useVersion(defaultVersion, nil); err != nil {
+				return fmt.Errorf("failed to switch to version %s: %w", defaultVersion, err)
+			}
 		}
 	}
 
-	err = useVersion(version, nil)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d versions failed to install", failed, len(results))
+	}
+	return nil
+}
+
+// runInstalls installs resolved versions, at most jobs at a time, without
+// switching to any of them
+func runInstalls(versions []string, jobs int) []installResult {
+	if jobs < 1 {
+		jobs = 1
+	}
+	results := make([]installResult, len(versions))
+	slots := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for i, version := range versions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			fmt.Printf("[%s] installing...\n", version)
+			result := installResult{version: version}
+			versionStart := time.Now()
+			if version == "nightly" {
+				_, result.installed, result.err = installNightlyBuild(installAppImage)
+			} else {
+				result.installed, result.err = installStable(version)
+			}
+			result.elapsed = time.Since(versionStart).Round(time.Millisecond)
+			if result.err != nil {
+				color.Red("[%s] failed: %v", version, result.err)
+			} else {
+				fmt.Printf("[%s] done in %v\n", version, result.elapsed)
+			}
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// readVersionList reads versions from a file, separated by spaces or
+// newlines. Everything after a # is a comment.
+func readVersionList(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return fmt.Errorf("failed to switch to version %s: %w", version, err)
+		return nil, fmt.Errorf("failed to read version list: %w", err)
+	}
+
+	var versions []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		versions = append(versions, strings.Fields(line)...)
+	}
+	return versions, nil
+}
+
+// installAsset downloads a release tarball into targetDir and extracts it,
+// returning the archive's root directory
+func installAsset(source utils.ReleaseSource, release utils.ReleaseInfo, asset utils.ReleaseAsset, targetDir string) (string, error) {
+	archivePath := filepath.Join(targetDir, asset.Name)
+	if err := downloadVerified(source, release, asset, archivePath); err != nil {
+		return "", err
+	}
+
+	rootDir, err := utils.ExtractTarGz(archivePath, targetDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to extract Neovim: %w", err)
+	}
+
+	// Remove the downloaded archive
+	if err = os.Remove(archivePath); err != nil {
+		warnf("Warning (non-fatal): Failed to remove Neovim archive: %v", err)
+	}
+	return rootDir, nil
+}
+
+// downloadVerified downloads an asset, or takes it from the artifact cache,
+// and checks it against the checksum its source publishes when there is one
+func downloadVerified(source utils.ReleaseSource, release utils.ReleaseInfo, asset utils.ReleaseAsset, filePath string) error {
+	checksum, err := source.Checksum(release, asset)
+	if err != nil {
+		warnf("Warning: could not verify the download: %v", err)
+		checksum = ""
+	}
+
+	// The nightly assets keep their URL from one build to the next
+	if err = download(asset.BrowserDownloadURL, checksum, filePath, release.TagName != "nightly"); err != nil {
+		return fmt.Errorf("failed to download Neovim: %w", err)
 	}
-	green := color.New(color.FgCyan).PrintfFunc()
-	green("Neovim version %s installed successfully!\n", version)
 	return nil
 }
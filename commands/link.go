@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"fmt"
+	"nvm_manager_go/utils"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var LinkCmd = &cobra.Command{
+	Use:   "link <name> <path>",
+	Short: "Register an externally-built Neovim as a managed version",
+	Long: `Register a Neovim that nea didn't install (Homebrew, distro packages,
+a local 'make install' tree, ...) so it can be used like any other version.
+
+<path> can be the nvim binary itself or an installation prefix containing bin/nvim.
+Linked versions are never deleted by 'clean', only unregistered.`,
+	Args: cobra.ExactArgs(2),
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		utils.Setup()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := linkVersion(args[0], args[1]); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+	},
+}
+
+func linkVersion(name, path string) error {
+	if err := utils.ValidateLinkName(name); err != nil {
+		return fmt.Errorf("invalid name: %w", err)
+	}
+
+	binary, prefix, err := utils.FindNvimBinary(path)
+	if err != nil {
+		return err
+	}
+
+	// Make sure it actually runs before registering it
+	version, err := utils.NvimVersion(binary)
+	if err != nil {
+		return err
+	}
+
+	if err = utils.RegisterLinkedVersion(name, prefix, binary, version); err != nil {
+		return err
+	}
+
+	color.Green("Linked %s (%s) as '%s'", binary, version, name)
+	fmt.Printf("Use 'nea use %s' to switch to it.\n", name)
+	return nil
+}
+
+func unlinkVersion(name string) error {
+	current, _ := utils.DetermineCurrentVersion()
+	if err := utils.UnregisterLinkedVersion(name); err != nil {
+		return err
+	}
+
+	if current == name {
+		color.Yellow("Note: '%s' is still the active version, switch to another one with 'nea use'.", name)
+	}
+	fmt.Printf("Unregistered linked version %s (files were left untouched)\n", name)
+	return nil
+}
@@ -1,10 +1,7 @@
 package commands
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"nvm_manager_go/utils"
 	"os"
 	"sort"
@@ -17,16 +14,14 @@ import (
 	"golang.org/x/mod/semver"
 )
 
-type Tag struct {
-	Name string `json:"name"`
-}
-
 // Define a struct to hold version info for the table
 type VersionData struct {
 	Version string
 	Status  string
 }
 
+var listLong bool
+
 var ListCmd = &cobra.Command{
 	Use:   "ls [local|remote] [count]",
 	Short: "List all Neovim versions",
@@ -37,6 +32,7 @@ Usage:
                          (By default shows all stable versions and 5 most recent nightly versions)
                          (Optional: specify max count of nightly versions to show)
                          (Use -1 to show ALL nightly versions)
+                         (--long adds the commit, build type and LuaJIT of each build)
   nvm ls remote [count] - List remote available versions
                          (By default shows 7 most recent versions)
                          (Optional: specify max count to show)
@@ -72,6 +68,26 @@ Usage:
 	},
 }
 
+func init() {
+	ListCmd.Flags().BoolVarP(&listLong, "long", "l", false, "show the commit, build type and LuaJIT of local versions")
+}
+
+// buildColumns are the --long columns of a local version
+func buildColumns(build *utils.BuildInfo) []string {
+	if !listLong {
+		return nil
+	}
+	if build == nil {
+		// Installed before provenance was recorded, 'nea repair' fills it in
+		return []string{"?", "?", "?"}
+	}
+	commit := build.Commit
+	if commit == "" {
+		commit = build.Version
+	}
+	return []string{commit, build.BuildType, build.LuaJIT}
+}
+
 // Display remote available versions
 func listHandler(args []string) {
 	numVersions := 7 // Default number of versions to list
@@ -89,26 +105,12 @@ func listHandler(args []string) {
 		}
 	}
 
-	// Fetch the tags from GitHub API
-	resp, err := http.Get("https://api.github.com/repos/neovim/neovim/tags")
+	// Fetch the tags from the stable release source
+	tags, err := stableSource.ListTags()
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "Failed to fetch Neovim versions:", err)
 		return
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Println("Failed to read response body:", err)
-		return
-	}
-
-	var tags []Tag
-	err = json.Unmarshal(body, &tags)
-	if err != nil {
-		fmt.Println("Failed to decode JSON:", err)
-		return
-	}
 
 	// Create a nice table for the output
 	tableString := &strings.Builder{}
@@ -172,7 +174,19 @@ func listHandlerLocal(count int) {
 
 	tableString := &strings.Builder{}
 	table := tablewriter.NewWriter(tableString)
-	table.SetHeader([]string{"Version", "Created At", "Rollback Step", "Status"})
+	header := []string{"Version", "Created At", "Rollback Step", "Status"}
+	if listLong {
+		header = append(header, "Commit", "Build Type", "LuaJIT")
+	}
+	table.SetHeader(header)
+
+	// Compilation flags don't fit in the table, they are listed below it
+	var flags []string
+	addFlags := func(label string, build *utils.BuildInfo) {
+		if listLong && build != nil && build.Flags != "" {
+			flags = append(flags, fmt.Sprintf("%s: %s", label, build.Flags))
+		}
+	}
 
 	// Read the stable versions from the directory
 	stableVersions, err := os.ReadDir(targetDirStable)
@@ -192,7 +206,25 @@ func listHandlerLocal(count int) {
 			if versionName == currentVersion {
 				status = "used"
 			}
-			table.Append([]string{versionName, "", "N/A", status})
+			var build *utils.BuildInfo
+			if entry, ok := utils.FindStableVersion(versionName); ok {
+				build = entry.Build
+			}
+			table.Append(append([]string{versionName, "", "N/A", status}, buildColumns(build)...))
+			addFlags(versionName, build)
+		}
+	}
+
+	// Linked versions are shown by name along with the version they report
+	linkedVersions, err := utils.ReadLinkedVersions()
+	if err == nil {
+		for _, linked := range linkedVersions {
+			status := "linked"
+			if linked.Name == currentVersion {
+				status = "used"
+			}
+			table.Append(append([]string{fmt.Sprintf("%s (%s)", linked.Name, linked.Version), "", "N/A", status}, buildColumns(linked.Build)...))
+			addFlags(linked.Name, linked.Build)
 		}
 	}
 
@@ -229,13 +261,21 @@ func listHandlerLocal(count int) {
 			status = "used"
 		}
 
-		table.Append([]string{versionName, createdAt, fmt.Sprint(version.UniqueNumber), status})
+		table.Append(append([]string{versionName, createdAt, fmt.Sprint(version.UniqueNumber), status}, buildColumns(version.Build)...))
+		addFlags("nightly "+createdAt, version.Build)
 	}
 
-	table.Append([]string{"", "", "", ""})
-	table.Append([]string{"Total\n(nightlies)", fmt.Sprintf("%d", len(versions)), "", ""})
+	padding := make([]string, len(header)-2)
+	table.Append(make([]string, len(header)))
+	table.Append(append([]string{"Total\n(nightlies)", fmt.Sprintf("%d", len(versions))}, padding...))
 
 	table.Render()
 
 	fmt.Println(tableString.String())
+	if len(flags) > 0 {
+		fmt.Println("Compilation flags:")
+		for _, line := range flags {
+			fmt.Println("  " + line)
+		}
+	}
 }
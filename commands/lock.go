@@ -0,0 +1,320 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"nvm_manager_go/utils"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var lockOutput string
+
+var LockCmd = &cobra.Command{
+	Use:   "lock [versions...]",
+	Short: "Pin versions to their exact builds in nea.lock",
+	Long: `Resolve versions to the exact builds they point to right now and write
+them to nea.lock: the release node ID, the commit it was built from, and
+the URL and SHA-256 of every platform's asset. 'nea install --locked'
+then installs exactly those builds on any machine.
+
+Without arguments, the versions and default of nea.toml are locked. The
+lockfile is written next to nea.toml, or in the current directory.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		utils.Setup()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := lockVersions(args); err != nil {
+			fmt.Println("Error:", err)
+		}
+	},
+}
+
+func init() {
+	LockCmd.Flags().StringVarP(&lockOutput, "output", "o", "", "lockfile to write")
+}
+
+func lockVersions(versions []string) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	output := lockOutput
+	if output == "" {
+		output = filepath.Join(cwd, utils.LockfileName)
+	}
+
+	lockfile := utils.Lockfile{GeneratedAt: time.Now().UTC().Format(time.RFC3339)}
+	if len(versions) == 0 {
+		manifestPath, err := utils.FindManifest(cwd)
+		if err != nil {
+			return fmt.Errorf("nothing to lock: pass versions or add a %s (%w)", utils.ManifestName, err)
+		}
+		manifest, err := utils.ReadManifest(manifestPath)
+		if err != nil {
+			return err
+		}
+		versions = append(versions, manifest.Stable...)
+		if manifest.Nightly {
+			versions = append(versions, "nightly")
+		}
+		if manifest.Default != "" {
+			versions = append(versions, manifest.Default)
+			lockfile.Default = manifest.Default
+		}
+		if lockOutput == "" {
+			output = filepath.Join(filepath.Dir(manifestPath), utils.LockfileName)
+		}
+	}
+
+	var resolved []string
+	for _, version := range versions {
+		if version, err = utils.ResolveVersion(stableSource, version); err != nil {
+			return err
+		}
+		if !slices.Contains(resolved, version) {
+			resolved = append(resolved, version)
+		}
+	}
+	if lockfile.Default != "" {
+		lockfile.Default, _ = utils.ResolveVersion(stableSource, lockfile.Default)
+	}
+
+	for _, version := range resolved {
+		build, err := lockBuild(version)
+		if err != nil {
+			return fmt.Errorf("failed to lock %s: %w", version, err)
+		}
+		lockfile.Releases = append(lockfile.Releases, build)
+		fmt.Printf("Locked %s to %s (%d assets)\n", version, shortCommit(build), len(build.Assets))
+	}
+
+	if err = utils.WriteLockfile(output, lockfile); err != nil {
+		return err
+	}
+	color.Green("Wrote %s", output)
+	return nil
+}
+
+// lockBuild resolves a version to the release it currently points to
+func lockBuild(version string) (utils.LockedBuild, error) {
+	source, tag := stableSource, "v"+version
+	if version == "nightly" {
+		source, tag = nightlySource, "nightly"
+	}
+
+	release, err := source.Release(tag)
+	if err != nil {
+		return utils.LockedBuild{}, err
+	}
+	build := utils.LockedBuild{Version: version, Tag: release.TagName, NodeID: release.NodeID, CreatedAt: release.CreatedAt}
+	if build.Tag == "" {
+		build.Tag = tag
+	}
+	if commitSource, ok := source.(utils.CommitSource); ok {
+		if build.Commit, err = commitSource.Commit(release); err != nil {
+			fmt.Println("Warning: could not resolve the commit:", err)
+		}
+	}
+
+	// The asset of this platform is hashed here when its source publishes no
+	// checksum, those of other platforms can't be locked without one
+	current, _ := utils.ResolveAsset(release.Assets, utils.CurrentPlatform(), utils.FormatTarball)
+	for _, asset := range release.Assets {
+		if !strings.HasSuffix(asset.Name, utils.FormatTarball) && !strings.HasSuffix(asset.Name, utils.FormatAppImage) {
+			continue
+		}
+		checksum, err := source.Checksum(release, asset)
+		if err != nil {
+			return build, err
+		}
+		if checksum == "" && asset.Name == current.Name {
+			if checksum, err = hashAsset(asset, tag); err != nil {
+				return build, err
+			}
+		}
+		if checksum == "" {
+			color.Yellow("Skipping %s, its source publishes no checksum", asset.Name)
+			continue
+		}
+		build.Assets = append(build.Assets, utils.LockedAsset{Name: asset.Name, URL: asset.BrowserDownloadURL, SHA256: checksum})
+	}
+	if len(build.Assets) == 0 {
+		return build, fmt.Errorf("no asset of %s could be locked", tag)
+	}
+	return build, nil
+}
+
+// hashAsset downloads an asset, through the artifact cache, to hash it
+func hashAsset(asset utils.ReleaseAsset, tag string) (string, error) {
+	workDir, err := os.MkdirTemp("", "nea-lock-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	filePath := filepath.Join(workDir, asset.Name)
+	if err = download(asset.BrowserDownloadURL, "", filePath, tag != "nightly"); err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", asset.Name, err)
+	}
+	return utils.FileSHA256(filePath)
+}
+
+func shortCommit(build utils.LockedBuild) string {
+	if len(build.Commit) >= 12 {
+		return build.Commit[:12]
+	}
+	return build.NodeID
+}
+
+// lockedSource serves a locked build, with the checksums of the lockfile,
+// from the URLs it was locked with or from the configured source
+type lockedSource struct {
+	utils.ReleaseSource
+	build utils.LockedBuild
+}
+
+func (l *lockedSource) Release(tag string) (utils.ReleaseInfo, error) {
+	return l.build.Release(), nil
+}
+
+func (l *lockedSource) Checksum(release utils.ReleaseInfo, asset utils.ReleaseAsset) (string, error) {
+	for _, locked := range l.build.Assets {
+		if locked.Name == asset.Name {
+			return locked.SHA256, nil
+		}
+	}
+	return "", fmt.Errorf("%s is not in the lockfile", asset.Name)
+}
+
+// installLocked installs the builds of the closest nea.lock, or only the
+// requested versions of it, and switches to its default
+func installLocked(versions []string) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	path, err := utils.FindLockfile(cwd)
+	if err != nil {
+		return err
+	}
+	lockfile, err := utils.ReadLockfile(path)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Installing from", path)
+
+	var builds []utils.LockedBuild
+	for _, version := range versions {
+		version = strings.TrimPrefix(version, "v")
+		index := slices.IndexFunc(lockfile.Releases, func(b utils.LockedBuild) bool { return b.Version == version })
+		if index < 0 {
+			return fmt.Errorf("%s is not in %s, run 'nea lock' to add it", version, path)
+		}
+		builds = append(builds, lockfile.Releases[index])
+	}
+	if len(versions) == 0 {
+		builds = lockfile.Releases
+	}
+
+	failed := 0
+	for _, build := range builds {
+		if err = installLockedBuild(build); err != nil {
+			color.Red("[%s] failed: %v", build.Version, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d locked versions failed to install", failed, len(builds))
+	}
+
+	defaultVersion := installDefault
+	if defaultVersion == "" {
+		defaultVersion = lockfile.Default
+	}
+	if defaultVersion != "" {
+		if err = useVersion(defaultVersion, nil); err != nil {
+			return fmt.Errorf("failed to switch to version %s: %w", defaultVersion, err)
+		}
+		fmt.Println("Using", defaultVersion)
+	}
+	return nil
+}
+
+// installLockedBuild installs exactly the locked asset of this platform. It
+// comes from the artifact cache, the locked URL or the configured source,
+// whichever has it with the locked checksum.
+func installLockedBuild(build utils.LockedBuild) error {
+	format := utils.FormatTarball
+	if installAppImage {
+		format = utils.FormatAppImage
+	}
+	release := build.Release()
+	asset, err := utils.ResolveCompatibleAsset(release.Assets, utils.CurrentPlatform(), format, build.Version)
+	if err != nil {
+		return fmt.Errorf("the lockfile has no build for this platform: %w", err)
+	}
+
+	configured := stableSource
+	if build.Version == "nightly" {
+		configured = nightlySource
+	}
+	source := &lockedSource{ReleaseSource: configured, build: build}
+
+	install := func(asset utils.ReleaseAsset) (bool, error) {
+		if build.Version == "nightly" {
+			return installNightlyRelease(source, release, asset, false)
+		}
+		if _, err := os.Stat(filepath.Join(targetDirStable, build.Version)); err == nil {
+			fmt.Println("Version", build.Version, "is already installed.")
+			return false, nil
+		}
+		return installStableRelease(source, build.Version, release, asset, false)
+	}
+
+	fmt.Printf("[%s] installing %s (%s)...\n", build.Version, asset.Name, shortCommit(build))
+	installed, err := install(asset)
+	if err != nil {
+		// The locked URL may be gone, the configured source (e.g. a mirror)
+		// may still have the same file
+		fallback, found := configuredAsset(configured, build.Tag, asset.Name)
+		if !found || fallback.BrowserDownloadURL == asset.BrowserDownloadURL {
+			return lockedUnavailable(err)
+		}
+		fmt.Printf("[%s] %v, trying %s\n", build.Version, err, fallback.BrowserDownloadURL)
+		if installed, err = install(fallback); err != nil {
+			return lockedUnavailable(err)
+		}
+	}
+	if installed {
+		color.Green("[%s] installed", build.Version)
+	}
+	return nil
+}
+
+// configuredAsset looks up an asset by name in the configured source
+func configuredAsset(source utils.ReleaseSource, tag, name string) (utils.ReleaseAsset, bool) {
+	release, err := source.Release(tag)
+	if err != nil {
+		return utils.ReleaseAsset{}, false
+	}
+	for _, asset := range release.Assets {
+		if asset.Name == name {
+			return asset, true
+		}
+	}
+	return utils.ReleaseAsset{}, false
+}
+
+func lockedUnavailable(err error) error {
+	if errors.Is(err, utils.ErrOffline) {
+		return fmt.Errorf("the locked build isn't cached: %w", err)
+	}
+	return fmt.Errorf("the locked build is no longer available from its URL, the configured source or the cache: %w", err)
+}
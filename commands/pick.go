@@ -0,0 +1,408 @@
+package commands
+
+import (
+	"fmt"
+	"nvm_manager_go/utils"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"golang.org/x/mod/semver"
+)
+
+var PickCmd = &cobra.Command{
+	Use:   "pick",
+	Short: "Pick a version to use, install, pin or remove from a list",
+	Long: `Browse installed and available versions in an interactive list.
+
+  ↑/↓ or j/k   move            /   filter (Enter or Esc to stop)
+  Enter or u   use / install   i   install
+  p            pin it in ./.nvim-version
+  d            remove          q   quit
+
+Installed nightlies are listed by date; using an older one rolls back to it.
+Installing and removing ask for confirmation first.`,
+	Args: cobra.NoArgs,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		utils.Setup()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := pick(); err != nil {
+			fmt.Println("Error:", err)
+		}
+	},
+}
+
+func pick() error {
+	if !interactive() {
+		return fmt.Errorf("nea pick needs a terminal")
+	}
+	state := &pickState{}
+	if err := state.run(); err != nil {
+		return err
+	}
+	// The picker's screen is gone, what it did stays in the scrollback
+	if state.used != "" {
+		color.Green("Now using %s", state.used)
+	}
+	return nil
+}
+
+// interactive reports whether both stdin and stdout are a terminal
+func interactive() bool {
+	_, _, err := utils.TerminalSize(int(os.Stdout.Fd()))
+	return err == nil && utils.IsTerminal(os.Stdin)
+}
+
+// pickItem is one line of the picker
+type pickItem struct {
+	label  string
+	status string
+	// kind is stable, nightly, custom or remote
+	kind    string
+	version string
+	// step is the rollback step of an installed nightly
+	step    int
+	current bool
+	pinned  bool
+}
+
+// pickState is the picker's list, cursor and filter
+type pickState struct {
+	items     []pickItem
+	filter    string
+	filtering bool
+	cursor    int
+	offset    int
+	message   string
+	// used is the version switched to, which ends the picker
+	used string
+}
+
+func (s *pickState) visible() []pickItem {
+	if s.filter == "" {
+		return s.items
+	}
+	var visible []pickItem
+	for _, item := range s.items {
+		if strings.Contains(strings.ToLower(item.label+" "+item.status), strings.ToLower(s.filter)) {
+			visible = append(visible, item)
+		}
+	}
+	return visible
+}
+
+func (s *pickState) run() error {
+	remote := pickRemoteVersions()
+	s.items = pickItems(remote)
+
+	restore, err := utils.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return err
+	}
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Print("\x1b[?25h\x1b[?1049l")
+		restore()
+	}()
+
+	buf := make([]byte, 16)
+	for {
+		s.render()
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return err
+		}
+		key := string(buf[:n])
+
+		visible := s.visible()
+		if s.filtering {
+			s.filterInput(buf[:n])
+			s.cursor, s.offset = 0, 0
+			continue
+		}
+
+		switch key {
+		case "q", "\x1b", "\x03":
+			return nil
+		case "j", "\x1b[B", "\x0e":
+			s.cursor = min(s.cursor+1, len(visible)-1)
+		case "k", "\x1b[A", "\x10":
+			s.cursor = max(s.cursor-1, 0)
+		case "/":
+			s.filtering = true
+		case "\r", "u", "i", "p", "d":
+			if len(visible) == 0 {
+				continue
+			}
+			item := visible[s.cursor]
+			if err := s.act(key, item, restore); err != nil {
+				s.message = color.RedString("Error: %v", err)
+			}
+			if s.used != "" {
+				return nil
+			}
+			s.items = pickItems(remote)
+			s.cursor = min(s.cursor, max(len(s.visible())-1, 0))
+		}
+	}
+}
+
+// filterInput edits the filter; fast typing or a paste comes in a single
+// read, so it goes byte by byte
+func (s *pickState) filterInput(input []byte) {
+	if len(input) > 1 && input[0] == '\x1b' {
+		// Arrows and other escape sequences
+		return
+	}
+	for _, b := range input {
+		switch {
+		case b == '\r' || b == '\x1b':
+			s.filtering = false
+			return
+		case b == 0x7f || b == '\b':
+			if len(s.filter) > 0 {
+				s.filter = s.filter[:len(s.filter)-1]
+			}
+		case b >= ' ' && b < 0x7f:
+			s.filter += string(b)
+		}
+	}
+}
+
+// act runs the action of a key on an item
+func (s *pickState) act(key string, item pickItem, restore func() error) error {
+	action := key
+	if key == "\r" || key == "u" {
+		action = "u"
+		if item.kind == "remote" {
+			action = "i"
+		}
+	}
+
+	switch action {
+	case "u":
+		if item.kind == "remote" {
+			return fmt.Errorf("%s isn't installed, press i to install it", item.label)
+		}
+		var err error
+		switch {
+		case item.kind == utils.KindNightly && item.step > 0:
+			err = RollbackVersion(item.step)
+		case item.kind == utils.KindNightly:
+			// Nightlies are listed by date, which use doesn't take
+			err = useVersion("nightly", nil)
+		default:
+			err = useVersion(item.version, nil)
+		}
+		if err != nil {
+			return err
+		}
+		s.used = item.label
+		return nil
+
+	case "i":
+		if item.kind != "remote" {
+			return fmt.Errorf("%s is already installed", item.label)
+		}
+		if !s.confirm(fmt.Sprintf("Install %s?", item.label)) {
+			return nil
+		}
+		return s.runOutside(restore, func() error {
+			var err error
+			if item.version == "nightly" {
+				_, _, err = installNightlyBuild(installAppImage)
+			} else {
+				_, err = installStable(item.version)
+			}
+			return err
+		})
+
+	case "p":
+		if item.kind == "remote" {
+			return fmt.Errorf("install %s before pinning it", item.label)
+		}
+		version := item.version
+		if item.kind == utils.KindNightly {
+			// .nvim-version can only follow the newest nightly
+			version = "nightly"
+		}
+		if err := os.WriteFile(utils.VersionFileName, []byte(version+"\n"), 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", utils.VersionFileName, err)
+		}
+		cwd, _ := os.Getwd()
+		s.message = color.GreenString("Pinned %s in %s", version, filepath.Join(cwd, utils.VersionFileName))
+		return nil
+
+	case "d":
+		if item.kind == "remote" {
+			return fmt.Errorf("%s isn't installed", item.label)
+		}
+		question := fmt.Sprintf("Remove %s?", item.label)
+		if item.current {
+			question = fmt.Sprintf("%s is in use, remove it anyway?", item.label)
+		}
+		if !s.confirm(question) {
+			return nil
+		}
+		return s.runOutside(restore, func() error {
+			switch item.kind {
+			case utils.KindNightly:
+				return cleanSpecificNightly(item.version)
+			case utils.KindCustom:
+				return unlinkVersion(item.version)
+			default:
+				return cleanSpecificStable(item.version)
+			}
+		})
+	}
+	return nil
+}
+
+// confirm asks a yes/no question on the picker's status line
+func (s *pickState) confirm(question string) bool {
+	s.message = color.YellowString("%s [y/N]", question)
+	s.render()
+	s.message = ""
+	buf := make([]byte, 16)
+	n, err := os.Stdin.Read(buf)
+	return err == nil && n == 1 && (buf[0] == 'y' || buf[0] == 'Y')
+}
+
+// runOutside leaves the picker's screen while an action prints its progress
+func (s *pickState) runOutside(restore func() error, action func() error) error {
+	fmt.Print("\x1b[?25h\x1b[?1049l")
+	restore()
+	err := action()
+	if err == nil {
+		s.message = color.GreenString("Done")
+	}
+	if _, rawErr := utils.MakeRaw(int(os.Stdin.Fd())); rawErr != nil && err == nil {
+		err = rawErr
+	}
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	return err
+}
+
+func (s *pickState) render() {
+	width, height, err := utils.TerminalSize(int(os.Stdout.Fd()))
+	if err != nil || height < 6 {
+		width, height = 80, 24
+	}
+	visible := s.visible()
+	rows := height - 4
+
+	if s.cursor < s.offset {
+		s.offset = s.cursor
+	}
+	if s.cursor >= s.offset+rows {
+		s.offset = s.cursor - rows + 1
+	}
+
+	var out strings.Builder
+	out.WriteString("\x1b[H\x1b[2J")
+	filter := s.filter
+	if s.filtering {
+		filter += "█"
+	}
+	fmt.Fprintf(&out, "%s  %s\r\n\r\n", color.New(color.Bold).Sprint("nea pick"), color.CyanString("/%s", filter))
+	for i := s.offset; i < len(visible) && i < s.offset+rows; i++ {
+		item := visible[i]
+		marker := " "
+		switch {
+		case item.current:
+			marker = color.GreenString("●")
+		case item.kind != "remote":
+			marker = "✓"
+		}
+		pin := ""
+		if item.pinned {
+			pin = color.CyanString(" (pinned)")
+		}
+		line := fmt.Sprintf("%s %-24s %s%s", marker, item.label, color.HiBlackString(item.status), pin)
+		if i == s.cursor {
+			line = "\x1b[7m" + line + "\x1b[0m"
+		}
+		out.WriteString(truncateLine(line, width) + "\r\n")
+	}
+	if len(visible) == 0 {
+		out.WriteString("  no version matches\r\n")
+	}
+	fmt.Fprintf(&out, "\x1b[%d;1H", height)
+	if s.message != "" {
+		out.WriteString(s.message)
+	} else {
+		out.WriteString(color.HiBlackString("enter use/install · i install · p pin · d remove · / filter · q quit"))
+	}
+	fmt.Print(out.String())
+}
+
+// truncateLine keeps a line from wrapping, a rough cut as colors count too
+func truncateLine(line string, width int) string {
+	if len([]rune(line)) > width+24 {
+		return string([]rune(line)[:width+24])
+	}
+	return line
+}
+
+// pickItems lists the installed versions, then the remote ones that aren't
+func pickItems(remote []string) []pickItem {
+	current, _ := utils.DetermineCurrentVersion()
+	pinned := ""
+	if cwd, err := os.Getwd(); err == nil {
+		if path, err := utils.FindVersionFile(cwd); err == nil {
+			pinned, _ = utils.ReadVersionFile(path)
+		}
+	}
+
+	var items []pickItem
+	stables, _ := utils.GetLocalStableVersions()
+	for _, version := range stables {
+		items = append(items, pickItem{label: version, status: "stable", kind: utils.KindStable, version: version,
+			current: version == current, pinned: version == pinned})
+	}
+
+	nightlies, _ := utils.ReadVersionsInfo()
+	for step, nightly := range nightlies {
+		status := "nightly"
+		if step > 0 {
+			status = fmt.Sprintf("nightly, rollback %d", step)
+		}
+		date := nightlyDate(nightly)
+		items = append(items, pickItem{label: "nightly " + date, status: status, kind: utils.KindNightly, version: date, step: step,
+			current: filepath.Base(nightly.Directory) == current, pinned: step == 0 && pinned == "nightly"})
+	}
+
+	linked, _ := utils.ReadLinkedVersions()
+	for _, entry := range linked {
+		items = append(items, pickItem{label: entry.Name, status: "linked " + entry.Version, kind: utils.KindCustom, version: entry.Name,
+			current: entry.Name == current, pinned: entry.Name == pinned})
+	}
+
+	items = append(items, pickItem{label: "nightly", status: "latest nightly build", kind: "remote", version: "nightly"})
+	for _, version := range remote {
+		if !slices.Contains(stables, version) {
+			items = append(items, pickItem{label: version, status: "available", kind: "remote", version: version})
+		}
+	}
+	return items
+}
+
+// pickRemoteVersions lists the stable releases, from the cache when the
+// network is unavailable
+func pickRemoteVersions() []string {
+	tags, err := stableSource.ListTags()
+	if err != nil {
+		return cachedRemoteVersions()
+	}
+	var versions []string
+	for _, tag := range tags {
+		if semver.IsValid(tag.Name) && semver.Prerelease(tag.Name) == "" {
+			versions = append(versions, strings.TrimPrefix(tag.Name, "v"))
+		}
+	}
+	return versions
+}
@@ -0,0 +1,411 @@
+package commands
+
+import (
+	"fmt"
+	"nvm_manager_go/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"golang.org/x/mod/semver"
+)
+
+var (
+	repairDryRun bool
+	repairForce  bool
+)
+
+// recentChange is how long an unregistered directory without a working
+// build is assumed to be an install in progress
+const recentChange = 10 * time.Minute
+
+var RepairCmd = &cobra.Command{
+	Use:   "repair",
+	Short: "Make versions_info.json match what is installed",
+	Long: `Reconcile the registry with the nightly/ and stable/ directories:
+
+- entries whose directory or binary is gone are dropped
+- duplicate entries of the same build are merged
+- directories holding a working build are registered again, identified
+  with 'nvim --version'; those without one are removed, unless they changed
+  in the last 10 minutes and may be an install in progress (--force removes
+  them too)
+- bin/nvim is pointed at an installed version when its target is gone
+
+Linked versions are reported but left alone. --dry-run only shows what
+would change.`,
+	Args: cobra.NoArgs,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		utils.Setup()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := repair(); err != nil {
+			fmt.Println("Error:", err)
+		}
+	},
+}
+
+func init() {
+	RepairCmd.Flags().BoolVar(&repairDryRun, "dry-run", false, "only report what would be repaired")
+	RepairCmd.Flags().BoolVar(&repairForce, "force", false, "also remove directories that changed recently")
+}
+
+// repairPlan is the registry as it should be and what it takes to get there
+type repairPlan struct {
+	findings   []string
+	registry   []utils.VersionInfo
+	removeDirs []string
+	// relink is the version to point bin/nvim at when its target is gone
+	relink     string
+	unlinkNvim bool
+	// backup is set when the registry can't be read, it is kept aside
+	backup bool
+}
+
+func (p *repairPlan) report(format string, args ...any) {
+	p.findings = append(p.findings, fmt.Sprintf(format, args...))
+}
+
+func repair() error {
+	plan, err := planRepair()
+	if err != nil {
+		return err
+	}
+	if len(plan.findings) == 0 {
+		color.Green("The registry matches what is installed, nothing to repair.")
+		return nil
+	}
+
+	for _, finding := range plan.findings {
+		fmt.Println("  " + finding)
+	}
+	if repairDryRun {
+		fmt.Printf("\n%d problem(s) found, run 'nea repair' to fix them.\n", len(plan.findings))
+		return nil
+	}
+	return applyRepair(plan)
+}
+
+func planRepair() (repairPlan, error) {
+	var plan repairPlan
+	entries, err := utils.ReadRegistry()
+	if err != nil {
+		// Everything on disk gets registered again below
+		plan.report("versions_info.json is unreadable (%v), rebuilding it from disk", err)
+		plan.backup = true
+		entries = nil
+	}
+
+	// handled holds the directories that are registered or being removed
+	handled := make(map[string]bool)
+	nodeIDs := keptNightlies(entries)
+	stables := make(map[string]bool)
+	for _, entry := range entries {
+		label := registryLabel(entry)
+		if entry.Kind == utils.KindCustom {
+			if _, err := os.Stat(entry.Binary); err != nil {
+				plan.report("%s points at %s, which is gone (left alone, 'nea clean %s' unlinks it)", label, entry.Binary, entry.Name)
+			}
+			plan.registry = append(plan.registry, entry)
+			continue
+		}
+
+		dir := filepath.Clean(entry.Directory)
+		if _, err := os.Stat(dir); err != nil {
+			plan.report("drop %s: %s is missing", label, entry.Directory)
+			continue
+		}
+		if handled[dir] {
+			plan.report("drop duplicate entry of %s", label)
+			continue
+		}
+		if entry.IsNightly() && entry.NodeID != "" {
+			if kept, ok := nodeIDs[entry.NodeID]; ok && kept != dir {
+				// The same build installed twice, the other copy stays
+				plan.report("remove %s: same build as %s", entry.Directory, kept)
+				plan.removeDirs = append(plan.removeDirs, dir)
+				handled[dir] = true
+				continue
+			}
+		}
+		if entry.Kind == utils.KindStable && stables[entry.Version] {
+			plan.report("drop duplicate entry of %s", label)
+			continue
+		}
+
+		if entry.Binary == "" || !fileExists(entry.Binary) {
+			binary, err := findExtractedBinary(dir)
+			if err != nil {
+				plan.report("drop %s and remove %s: no nvim binary in it", label, entry.Directory)
+				plan.removeDirs = append(plan.removeDirs, dir)
+				handled[dir] = true
+				continue
+			}
+			if entry.Binary != "" {
+				plan.report("update the binary of %s to %s", label, binary)
+			}
+			entry.Binary = binary
+			entry.RootDir = rootDirOf(dir, binary)
+		}
+
+		// Entries written before provenance was recorded get it now
+		if entry.Build == nil {
+			if entry.Build = buildInfo(entry.Binary); entry.Build != nil {
+				plan.report("record the build of %s (%s)", label, entry.Build.Version)
+			}
+		}
+
+		handled[dir] = true
+		if entry.Kind == utils.KindStable {
+			stables[entry.Version] = true
+		}
+		plan.registry = append(plan.registry, entry)
+	}
+
+	if err = planOrphans(&plan, targetNightly, handled, nightlyOrphan); err != nil {
+		return plan, err
+	}
+	if err = planOrphans(&plan, targetDirStable, handled, func(dir, binary, version string) (utils.VersionInfo, error) {
+		return stableOrphan(dir, binary, version, stables)
+	}); err != nil {
+		return plan, err
+	}
+
+	planRelink(&plan)
+	return plan, nil
+}
+
+// keptNightlies picks, for each build installed more than once, the
+// directory to keep: the one bin/nvim points into, or else the first one
+// holding a binary
+func keptNightlies(entries []utils.VersionInfo) map[string]string {
+	target, _ := os.Readlink(utils.SymlinkPath)
+	kept := make(map[string]string)
+	for _, entry := range entries {
+		if !entry.IsNightly() || entry.NodeID == "" {
+			continue
+		}
+		dir := filepath.Clean(entry.Directory)
+		if entry.Binary == "" || !fileExists(entry.Binary) {
+			if _, err := findExtractedBinary(dir); err != nil {
+				continue
+			}
+		}
+		inUse := target != "" && strings.HasPrefix(target, dir+string(filepath.Separator))
+		if _, ok := kept[entry.NodeID]; !ok || inUse {
+			kept[entry.NodeID] = dir
+		}
+	}
+	return kept
+}
+
+// planOrphans registers the builds found in directories of base the
+// registry doesn't know, and removes those without a working one. A
+// directory that changed recently may be an install still extracting, it
+// is only removed with --force.
+func planOrphans(plan *repairPlan, base string, handled map[string]bool, identify func(dir, binary, version string) (utils.VersionInfo, error)) error {
+	dirs, err := os.ReadDir(base)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", base, err)
+	}
+	for _, dir := range dirs {
+		path := filepath.Join(base, dir.Name())
+		if !dir.IsDir() || handled[path] {
+			continue
+		}
+		binary, err := findExtractedBinary(path)
+		if err != nil {
+			planOrphanRemoval(plan, path, "holds no nvim binary")
+			continue
+		}
+		version, err := utils.NvimVersion(binary)
+		if err != nil {
+			planOrphanRemoval(plan, path, fmt.Sprintf("its nvim doesn't run (%v)", err))
+			continue
+		}
+		entry, err := identify(path, binary, version)
+		if err != nil {
+			plan.report("leave %s alone: %v", path, err)
+			continue
+		}
+		entry.Directory = path
+		entry.Binary = binary
+		entry.RootDir = rootDirOf(path, binary)
+		entry.Build = buildInfo(binary)
+		plan.report("register %s as %s (%s)", path, registryLabel(entry), version)
+		plan.registry = append(plan.registry, entry)
+		handled[path] = true
+	}
+	return nil
+}
+
+// planOrphanRemoval removes an unregistered directory without a working
+// build, unless it may still be being installed
+func planOrphanRemoval(plan *repairPlan, path, reason string) {
+	if !repairForce && changedSince(path, time.Now().Add(-recentChange)) {
+		plan.report("leave %s alone: not registered and %s, but it changed in the last %s and may be an install in progress (--force removes it)", path, reason, recentChange)
+		return
+	}
+	plan.report("remove %s: not registered and %s", path, reason)
+	plan.removeDirs = append(plan.removeDirs, path)
+}
+
+// changedSince reports whether anything in the tree of dir was modified
+// after cutoff, an extraction updates the files it writes rather than dir
+func changedSince(dir string, cutoff time.Time) bool {
+	changed := false
+	filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil && info.ModTime().After(cutoff) {
+			changed = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return changed
+}
+
+// nightlyOrphan identifies a nightly by its directory, named after its
+// creation date by CreateTargetDirectory
+func nightlyOrphan(dir, binary, version string) (utils.VersionInfo, error) {
+	name := filepath.Base(dir)
+	created, err := time.Parse("2006-01-02-1504", name)
+	if err != nil {
+		if created, err = time.Parse("2006-01-02", name); err != nil {
+			return utils.VersionInfo{}, fmt.Errorf("'%s' is not a nightly directory name", name)
+		}
+	}
+	return utils.VersionInfo{Kind: utils.KindNightly, CreatedAt: created.UTC().Format(time.RFC3339), Version: version}, nil
+}
+
+// stableOrphan checks the binary of stable/<version> is that version
+func stableOrphan(dir, binary, version string, stables map[string]bool) (utils.VersionInfo, error) {
+	name := filepath.Base(dir)
+	if !strings.HasPrefix(version, "v"+name) {
+		return utils.VersionInfo{}, fmt.Errorf("it holds nvim %s, not %s", version, name)
+	}
+	if stables[name] {
+		return utils.VersionInfo{}, fmt.Errorf("%s is already registered elsewhere", name)
+	}
+	stables[name] = true
+	created := time.Now().UTC()
+	if fi, err := os.Stat(dir); err == nil {
+		created = fi.ModTime().UTC()
+	}
+	return utils.VersionInfo{Kind: utils.KindStable, Version: name, CreatedAt: created.Format(time.RFC3339)}, nil
+}
+
+// planRelink points bin/nvim at an installed version when it's broken:
+// one of the kind it pointed at, the newest stable or the newest nightly,
+// or else the newest of the other kind
+func planRelink(plan *repairPlan) {
+	target, err := os.Readlink(utils.SymlinkPath)
+	if err != nil || fileExists(target) && !willBeRemoved(plan, target) {
+		return
+	}
+
+	var newestStable, newestNightly utils.VersionInfo
+	for _, entry := range plan.registry {
+		switch {
+		case entry.Kind == utils.KindStable && (newestStable.Version == "" || semverLess(newestStable.Version, entry.Version)):
+			newestStable = entry
+		case entry.IsNightly() && entry.CreatedAt > newestNightly.CreatedAt:
+			newestNightly = entry
+		}
+	}
+	wasNightly := strings.HasPrefix(target, targetNightly+string(filepath.Separator))
+	switch {
+	case wasNightly && newestNightly.Directory != "":
+		plan.relink = "nightly"
+	case newestStable.Version != "":
+		plan.relink = newestStable.Version
+	case newestNightly.Directory != "":
+		plan.relink = "nightly"
+	default:
+		plan.report("remove %s: it points at %s, which is gone, and nothing else is installed", utils.SymlinkPath, target)
+		plan.unlinkNvim = true
+		return
+	}
+	plan.report("point %s at %s: %s is gone", utils.SymlinkPath, plan.relink, target)
+}
+
+func applyRepair(plan repairPlan) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if plan.backup {
+		if err := os.Rename(versionFilePath, versionFilePath+".bak"); err != nil {
+			return fmt.Errorf("failed to keep the unreadable registry aside: %w", err)
+		}
+		fmt.Println("The unreadable registry was kept as", versionFilePath+".bak")
+	}
+	for _, dir := range plan.removeDirs {
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", dir, err)
+		}
+	}
+
+	// Nightlies keep their rollback numbering
+	var nightlies []utils.VersionInfo
+	var others []utils.VersionInfo
+	for _, entry := range plan.registry {
+		if entry.IsNightly() {
+			nightlies = append(nightlies, entry)
+		} else {
+			others = append(others, entry)
+		}
+	}
+	utils.SortVersionsDesc(nightlies)
+	for i := range nightlies {
+		nightlies[i].UniqueNumber = i
+	}
+	if err := utils.WriteRegistry(append(others, nightlies...)); err != nil {
+		return err
+	}
+
+	if plan.unlinkNvim {
+		if err := os.Remove(utils.SymlinkPath); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", utils.SymlinkPath, err)
+		}
+	}
+	if plan.relink != "" {
+		if err := useVersion(plan.relink, nil); err != nil {
+			return fmt.Errorf("failed to switch to %s: %w", plan.relink, err)
+		}
+	}
+	color.Green("Repaired %d problem(s).", len(plan.findings))
+	return nil
+}
+
+func willBeRemoved(plan *repairPlan, path string) bool {
+	for _, dir := range plan.removeDirs {
+		if strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// rootDirOf returns the top-level directory of dir the binary was found in
+func rootDirOf(dir, binary string) string {
+	rel, err := filepath.Rel(dir, binary)
+	if err != nil {
+		return ""
+	}
+	root, _, _ := strings.Cut(rel, string(filepath.Separator))
+	return root
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func semverLess(a, b string) bool {
+	return semver.Compare("v"+a, "v"+b) < 0
+}
@@ -4,23 +4,30 @@ import (
 	"fmt"
 	"nvm_manager_go/utils"
 	"os"
-	"path/filepath"
 	"strconv"
-	"strings"
 
 	"github.com/spf13/cobra"
 )
 
 var RollbackCmd = &cobra.Command{
-	Use:   "rollback [steps]",
+	Use:   "rollback [steps|commit]",
 	Short: "Rollback to a previous version",
 	Args:  cobra.ExactArgs(1), // Ensure exactly one argument is provided
 	Run: func(cmd *cobra.Command, args []string) {
 		// Parse the first argument as an integer
 		rollbackStep, err := strconv.Atoi(args[0])
 		if err != nil {
-			fmt.Println("Error: The argument must be a number")
-			return
+			// or the commit an installed nightly was built from
+			step, _, ok := utils.FindNightlyByCommit(args[0])
+			if !ok {
+				fmt.Println("Error: The argument must be a number or the commit of an installed nightly")
+				return
+			}
+			if step == 0 {
+				fmt.Println("Error: This is the latest nightly version, use 'use nightly'")
+				return
+			}
+			rollbackStep = step
 		}
 
 		// Call RollbackVersion with the parsed integer
@@ -58,16 +65,12 @@ func RollbackVersion(rollbackStep int) error {
 	// 4. Get the version to rollback to
 	rollbackTarget := versionsInfo[rollbackStep]
 
-	// 5. detect filename of the archive
-	binaryNameArch, err := getArchiveFilename("nightly")
-	if err != nil {
-		return fmt.Errorf("failed to get filename: %w", err)
-	}
-	dirName := strings.TrimSuffix(binaryNameArch, ".tar.gz")
-
 	// why not using usevwrsion function
 	// 5. Use the version
-	neovimBinary = filepath.Join(rollbackTarget.Directory, dirName) + "/bin/nvim"
+	neovimBinary, err = nightlyBinary(rollbackTarget)
+	if err != nil {
+		return err
+	}
 	if _, err := os.Stat(neovimBinary); err != nil {
 		return fmt.Errorf("version %s is not installed: %w", rollbackTarget.CreatedAt, err)
 	}
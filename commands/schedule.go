@@ -0,0 +1,132 @@
+package commands
+
+import (
+	"fmt"
+	"nvm_manager_go/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	scheduleDaily     string
+	scheduleScheduler string
+)
+
+var ScheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Update the nightly in the background every day",
+	Long: `Register 'nea update nightly --quiet' to run every day with a systemd user
+timer, falling back to a crontab entry, or a launchd agent on macOS. Its
+output goes to the update log in nea's log directory.`,
+}
+
+var scheduleEnableCmd = &cobra.Command{
+	Use:   "enable",
+	Short: "Run the nightly update every day",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := enableSchedule(); err != nil {
+			fmt.Println("Error:", err)
+		}
+	},
+}
+
+var scheduleStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the scheduled update and its last log lines",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		showSchedule()
+	},
+}
+
+var scheduleDisableCmd = &cobra.Command{
+	Use:   "disable",
+	Short: "Stop the scheduled update",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		removed, err := utils.DisableSchedule()
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		if len(removed) == 0 {
+			fmt.Println("No scheduled update found.")
+			return
+		}
+		color.Green("Removed the scheduled update from %s", strings.Join(removed, ", "))
+	},
+}
+
+func init() {
+	scheduleEnableCmd.Flags().StringVar(&scheduleDaily, "daily", "07:00", "time of day to update at, as HH:MM")
+	scheduleEnableCmd.Flags().StringVar(&scheduleScheduler, "scheduler", "", "systemd, cron or launchd (detected by default)")
+	ScheduleCmd.AddCommand(scheduleEnableCmd, scheduleStatusCmd, scheduleDisableCmd)
+}
+
+func enableSchedule() error {
+	at, err := time.Parse("15:04", scheduleDaily)
+	if err != nil {
+		return fmt.Errorf("invalid time '%s', expected HH:MM", scheduleDaily)
+	}
+	scheduler := scheduleScheduler
+	if scheduler == "" {
+		if scheduler, err = utils.DetectScheduler(); err != nil {
+			return err
+		}
+	}
+
+	// The job runs without nea's PATH, so it needs the binary's full path
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the nea binary: %w", err)
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return fmt.Errorf("failed to locate the nea binary: %w", err)
+	}
+
+	// Only one registration at a time, even when switching schedulers
+	if _, err = utils.DisableSchedule(); err != nil {
+		return err
+	}
+	command := []string{exe, "update", "nightly", "--quiet"}
+	if err = utils.EnableSchedule(scheduler, at.Hour(), at.Minute(), command); err != nil {
+		return err
+	}
+	color.Green("The nightly will be updated every day at %s (%s)", at.Format("15:04"), scheduler)
+	fmt.Println("Log:", utils.UpdateLogPath)
+	return nil
+}
+
+func showSchedule() {
+	statuses := utils.ScheduleStatuses()
+	if len(statuses) == 0 {
+		fmt.Println("No scheduled update, use 'nea schedule enable' to add one.")
+		return
+	}
+	for _, status := range statuses {
+		color.Green("Scheduled with %s", status.Scheduler)
+		if status.Details != "" {
+			fmt.Println(status.Details)
+		}
+	}
+
+	data, err := os.ReadFile(utils.UpdateLogPath)
+	if err != nil {
+		fmt.Println("\nNo update has run yet.")
+		return
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) > 10 {
+		lines = lines[len(lines)-10:]
+	}
+	fmt.Printf("\nLast lines of %s:\n", utils.UpdateLogPath)
+	for _, line := range lines {
+		fmt.Println(" ", line)
+	}
+}
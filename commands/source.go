@@ -0,0 +1,113 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"nvm_manager_go/utils"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/fatih/color"
+)
+
+const sourceArchiveURL = "https://github.com/neovim/neovim/archive/refs/tags/"
+
+// sourceRootDir is where a source build is installed inside its version directory
+const sourceRootDir = "nvim"
+
+// shouldBuildFromSource explains why no prebuilt binary can be used and
+// decides whether to fall back to building from source: always with
+// --from-source, after asking on a terminal, never otherwise.
+func shouldBuildFromSource(err error) bool {
+	if !errors.Is(err, utils.ErrNoCompatibleBuild) {
+		return false
+	}
+
+	// Quiet runs report the error anyway and have no one to ask
+	if !quietOutput {
+		color.Yellow("%v", err)
+	}
+	if installFromSource {
+		return true
+	}
+	if quietOutput {
+		return false
+	}
+	if confirm("Build Neovim from source instead?") {
+		return true
+	}
+	fmt.Println("Run the install again with --from-source to build it from source.")
+	return false
+}
+
+// buildFromSource downloads the source of the given tag and installs it into
+// targetDir with neovim's own Makefile, returning the install root directory
+func buildFromSource(tag, targetDir string) (string, error) {
+	if err := checkBuildTools(); err != nil {
+		return "", err
+	}
+
+	archivePath := filepath.Join(targetDir, tag+"-source.tar.gz")
+	// A tag's archive never changes, except for the nightly one
+	if err := download(sourceArchiveURL+tag+".tar.gz", "", archivePath, tag != "nightly"); err != nil {
+		return "", fmt.Errorf("failed to download source: %w", err)
+	}
+	srcDir, err := utils.ExtractTarGz(archivePath, targetDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to extract source: %w", err)
+	}
+	if err = os.Remove(archivePath); err != nil {
+		warnf("Warning: failed to remove source archive: %v", err)
+	}
+
+	srcPath := filepath.Join(targetDir, srcDir)
+	if !quietOutput {
+		color.Cyan("Building Neovim %s from source, this can take a few minutes...", tag)
+	}
+	cmd := exec.Command("make",
+		"CMAKE_BUILD_TYPE=Release",
+		"CMAKE_INSTALL_PREFIX="+filepath.Join(targetDir, sourceRootDir),
+		"install")
+	cmd.Dir = srcPath
+	cmd.Stdout = os.Stdout
+	if quietOutput {
+		cmd.Stdout = io.Discard
+	}
+	cmd.Stderr = os.Stderr
+	if err = cmd.Run(); err != nil {
+		return "", fmt.Errorf("build failed, the source tree is kept in %s: %w", srcPath, err)
+	}
+
+	if err = os.RemoveAll(srcPath); err != nil {
+		warnf("Warning: failed to remove source tree: %v", err)
+	}
+	return sourceRootDir, nil
+}
+
+// checkBuildTools makes sure the tools neovim's build needs are installed
+func checkBuildTools() error {
+	var missing []string
+	for _, tool := range []string{"make", "cmake", "gettext"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			missing = append(missing, tool)
+		}
+	}
+
+	compilerFound := false
+	for _, compiler := range []string{"cc", "gcc", "clang"} {
+		if _, err := exec.LookPath(compiler); err == nil {
+			compilerFound = true
+			break
+		}
+	}
+	if !compilerFound {
+		missing = append(missing, "a C compiler")
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("building from source needs %v, see https://github.com/neovim/neovim/blob/master/BUILD.md#build-prerequisites", missing)
+	}
+	return nil
+}
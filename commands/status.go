@@ -0,0 +1,115 @@
+package commands
+
+import (
+	"fmt"
+	"nvm_manager_go/utils"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var StatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show which Neovim is in use and why",
+	Long: `Show the version bin/nvim points at and the build it is (commit, build
+type and LuaJIT), then what overrides it: the version 'nea env' selected
+for this shell and the closest .nvim-version.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := showStatus(); err != nil {
+			fmt.Println("Error:", err)
+		}
+	},
+}
+
+func showStatus() error {
+	if current, err := utils.DetermineCurrentVersion(); err != nil {
+		statusLine("In use", color.YellowString("nothing, pick a version with 'nea use'"))
+	} else {
+		binary, err := os.Readlink(utils.SymlinkPath)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", utils.SymlinkPath, err)
+		}
+		description, build := describeVersion(current)
+		if build == nil {
+			// Installed before provenance was recorded
+			build = buildInfo(binary)
+		}
+		statusLine("In use", description)
+		statusLine("Binary", binary)
+		if build != nil {
+			statusLine("Build", describeBuild(*build))
+		}
+	}
+
+	if version := os.Getenv("NEA_VERSION"); version != "" {
+		statusLine("Shell", fmt.Sprintf("%s, from 'nea env' (%s)", version, os.Getenv("NEA_BIN")))
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	versionFile, err := utils.FindVersionFile(cwd)
+	if err != nil {
+		return nil
+	}
+	version, err := utils.ReadVersionFile(versionFile)
+	if err != nil {
+		return err
+	}
+	line := fmt.Sprintf("%s, from %s", version, versionFile)
+	if _, err = installedBinary(version); err != nil {
+		line += " " + color.RedString("(%v)", err)
+	}
+	statusLine("Project", line)
+	return nil
+}
+
+// describeVersion names the version DetermineCurrentVersion returned along
+// with its kind, and returns its recorded build
+func describeVersion(current string) (string, *utils.BuildInfo) {
+	if linked, ok := utils.FindLinkedVersion(current); ok {
+		return fmt.Sprintf("%s (linked, %s)", linked.Name, linked.Version), linked.Build
+	}
+	if strings.HasPrefix(current, "20") {
+		versions, _ := utils.ReadVersionsInfo()
+		for step, version := range versions {
+			if filepath.Base(version.Directory) != current {
+				continue
+			}
+			if step == 0 {
+				return fmt.Sprintf("nightly %s (newest)", nightlyDate(version)), version.Build
+			}
+			return fmt.Sprintf("nightly %s (rollback %d)", nightlyDate(version), step), version.Build
+		}
+		return "nightly " + current, nil
+	}
+	if entry, ok := utils.FindStableVersion(current); ok {
+		return current + " (stable)", entry.Build
+	}
+	return current + " (stable)", nil
+}
+
+// describeBuild is the provenance line of status, the commit standing in
+// for the version of development builds
+func describeBuild(build utils.BuildInfo) string {
+	parts := []string{build.Version}
+	if build.Commit != "" {
+		parts[0] = "commit " + build.Commit
+	}
+	if build.BuildType != "" {
+		parts = append(parts, build.BuildType)
+	}
+	if build.LuaJIT != "" {
+		parts = append(parts, "LuaJIT "+build.LuaJIT)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func statusLine(label, value string) {
+	fmt.Printf("%-8s %s\n", label, value)
+}
@@ -0,0 +1,304 @@
+package commands
+
+import (
+	"fmt"
+	"nvm_manager_go/utils"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	syncFile   string
+	syncPrune  bool
+	syncDryRun bool
+)
+
+var SyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Install the versions declared in nea.toml",
+	Long: `Make the installed versions match a nea.toml manifest, looked up in the
+current directory and its parents:
+
+  default = "0.10.4"                # version to use once synced
+  stable = ["0.9.5", "0.10.4"]      # "stable" means the latest release
+  nightly = true                    # track the latest nightly
+  nightly_pins = ["2025-01-15"]     # nightlies to keep
+
+Missing versions are installed and the default is activated. With --prune,
+stables and nightlies the manifest doesn't declare are removed; linked
+versions are never touched. Only the current nightly can be downloaded,
+so a pinned nightly that isn't installed is reported, not installed.`,
+	Args: cobra.NoArgs,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		utils.Setup()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := syncManifest(); err != nil {
+			fmt.Println("Error:", err)
+		}
+	},
+}
+
+func init() {
+	SyncCmd.Flags().StringVarP(&syncFile, "file", "f", "", "manifest to sync (default: nea.toml in the current directory or a parent)")
+	SyncCmd.Flags().BoolVar(&syncPrune, "prune", false, "remove installed versions the manifest doesn't declare")
+	SyncCmd.Flags().BoolVar(&syncDryRun, "dry-run", false, "only show what would change")
+}
+
+// syncPlan is what sync has to do to match a manifest
+type syncPlan struct {
+	install       []string
+	removeStable  []string
+	removeNightly []string
+	missingPins   []string
+	// defaultVersion is the resolved default, useDefault is set when
+	// switching to it is needed
+	defaultVersion string
+	useDefault     string
+}
+
+func syncManifest() error {
+	path := syncFile
+	if path == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		if path, err = utils.FindManifest(cwd); err != nil {
+			return err
+		}
+	}
+	manifest, err := utils.ReadManifest(path)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Syncing", manifest.Path)
+
+	plan, err := planSync(manifest)
+	if err != nil {
+		return err
+	}
+	if syncDryRun {
+		printSyncPlan(plan)
+		return nil
+	}
+	return applySync(plan)
+}
+
+func planSync(manifest utils.Manifest) (syncPlan, error) {
+	var plan syncPlan
+	nightlies, err := utils.ReadVersionsInfo()
+	if err != nil {
+		return plan, fmt.Errorf("failed to read versions info: %w", err)
+	}
+	current, _ := utils.DetermineCurrentVersion()
+
+	// The default counts as declared, so it gets installed and is never pruned
+	stables := manifest.Stable
+	if manifest.Default != "" {
+		target := manifest.Default
+		if target != "nightly" {
+			if _, linked := utils.FindLinkedVersion(target); !linked {
+				resolved, err := utils.ResolveVersion(stableSource, target)
+				if err != nil {
+					return plan, fmt.Errorf("invalid default version: %w", err)
+				}
+				target = resolved
+				stables = append(stables, target)
+			}
+		}
+		plan.defaultVersion = target
+		// A nightly in use is named after its directory, it is what "nightly"
+		// selects when it is the newest
+		inUse := current
+		if len(nightlies) > 0 && current == filepath.Base(nightlies[0].Directory) {
+			inUse = "nightly"
+		}
+		if inUse != target {
+			plan.useDefault = target
+		}
+	}
+
+	installedStables, _ := utils.GetLocalStableVersions()
+	declared := make([]string, 0, len(stables))
+	for _, version := range stables {
+		resolved, err := utils.ResolveVersion(stableSource, version)
+		if err != nil {
+			return plan, fmt.Errorf("invalid stable version %s: %w", version, err)
+		}
+		if slices.Contains(declared, resolved) {
+			continue
+		}
+		declared = append(declared, resolved)
+		if !slices.Contains(installedStables, resolved) {
+			plan.install = append(plan.install, resolved)
+		}
+	}
+	if manifest.Nightly {
+		// Whether it is new is only known once the release is fetched
+		plan.install = append(plan.install, "nightly")
+	}
+
+	for _, pin := range manifest.NightlyPins {
+		if _, found := findNightlyVersion(nightlies, pin); !found {
+			plan.missingPins = append(plan.missingPins, pin)
+		}
+	}
+
+	if syncPrune {
+		// The version in use is kept, bin/nvim would point at nothing if
+		// switching to the default failed
+		for _, version := range installedStables {
+			if !slices.Contains(declared, version) && version != current {
+				plan.removeStable = append(plan.removeStable, version)
+			}
+		}
+		// A tracked nightly keeps its rollback history, a default one the
+		// newest build
+		if !manifest.Nightly {
+			for i, nightly := range nightlies {
+				date := nightlyDate(nightly)
+				if slices.Contains(manifest.NightlyPins, date) || filepath.Base(nightly.Directory) == current ||
+					i == 0 && plan.defaultVersion == "nightly" {
+					continue
+				}
+				plan.removeNightly = append(plan.removeNightly, date)
+			}
+		}
+	}
+
+	return plan, nil
+}
+
+func printSyncPlan(plan syncPlan) {
+	changes := 0
+	for _, version := range plan.install {
+		if version == "nightly" {
+			fmt.Println("  + nightly (if a newer one is published)")
+		} else {
+			color.Green("  + %s", version)
+		}
+		changes++
+	}
+	for _, version := range plan.removeStable {
+		color.Red("  - %s", version)
+		changes++
+	}
+	for _, date := range plan.removeNightly {
+		color.Red("  - nightly %s", date)
+		changes++
+	}
+	if plan.useDefault != "" {
+		color.Cyan("  ~ use %s", plan.useDefault)
+		changes++
+	}
+	for _, pin := range plan.missingPins {
+		color.Yellow("  ! nightly %s is pinned but not installed, it can't be downloaded anymore", pin)
+	}
+	if changes == 0 {
+		fmt.Println("Everything is in sync.")
+	}
+}
+
+func applySync(plan syncPlan) error {
+	var added, removed, failed []string
+
+	defaultFailed := false
+	for _, result := range runInstalls(plan.install, installJobs) {
+		switch {
+		case result.err != nil:
+			failed = append(failed, fmt.Sprintf("%s: %v", result.version, result.err))
+			defaultFailed = defaultFailed || result.version == plan.defaultVersion
+		case result.installed:
+			added = append(added, result.version)
+		}
+	}
+
+	// A new nightly has to be switched to even if nightly was already in use
+	if plan.defaultVersion == "nightly" && slices.Contains(added, "nightly") {
+		plan.useDefault = "nightly"
+	}
+
+	// Switch before pruning so the active version never disappears
+	if plan.useDefault != "" && !defaultFailed {
+		if err := useVersion(plan.useDefault, nil); err != nil {
+			failed = append(failed, fmt.Sprintf("use %s: %v", plan.useDefault, err))
+			plan.useDefault = ""
+		}
+	} else {
+		plan.useDefault = ""
+	}
+
+	// The switch may have failed, the version in use is kept regardless
+	current, _ := utils.DetermineCurrentVersion()
+	nightlies, _ := utils.ReadVersionsInfo()
+	var kept []string
+	for _, version := range plan.removeStable {
+		if version == current {
+			kept = append(kept, version)
+			continue
+		}
+		if err := cleanSpecificStable(version); err != nil {
+			failed = append(failed, fmt.Sprintf("remove %s: %v", version, err))
+			continue
+		}
+		removed = append(removed, version)
+	}
+	for _, date := range plan.removeNightly {
+		if i, found := findNightlyVersion(nightlies, date); found && filepath.Base(nightlies[i].Directory) == current {
+			kept = append(kept, "nightly "+date)
+			continue
+		}
+		if err := cleanSpecificNightly(date); err != nil {
+			failed = append(failed, fmt.Sprintf("remove nightly %s: %v", date, err))
+			continue
+		}
+		removed = append(removed, "nightly "+date)
+	}
+
+	// The freshly installed nightly may be one of the pins
+	nightlies, _ = utils.ReadVersionsInfo()
+	fmt.Println()
+	for _, version := range added {
+		color.Green("  + %s", version)
+	}
+	for _, version := range removed {
+		color.Red("  - %s", version)
+	}
+	if plan.useDefault != "" {
+		color.Cyan("  ~ using %s", plan.useDefault)
+	}
+	for _, version := range kept {
+		color.Yellow("  ! kept %s, it is in use", version)
+	}
+	for _, pin := range plan.missingPins {
+		if _, found := findNightlyVersion(nightlies, pin); !found {
+			color.Yellow("  ! nightly %s is pinned but not installed, it can't be downloaded anymore", pin)
+		}
+	}
+	for _, failure := range failed {
+		color.Red("  x %s", failure)
+	}
+	if len(added)+len(removed) == 0 && plan.useDefault == "" && len(failed) == 0 {
+		fmt.Println("Everything is in sync.")
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("sync finished with %d error(s)", len(failed))
+	}
+	return nil
+}
+
+// nightlyDate returns the day a nightly was built, as used by clean
+func nightlyDate(version utils.VersionInfo) string {
+	t, err := time.Parse(time.RFC3339, version.CreatedAt)
+	if err != nil {
+		return version.CreatedAt
+	}
+	return t.Format("2006-01-02")
+}
@@ -0,0 +1,221 @@
+package commands
+
+import (
+	"fmt"
+	"nvm_manager_go/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"golang.org/x/mod/semver"
+)
+
+var (
+	updateNoSwitch bool
+	updateClean    bool
+	updateQuiet    bool
+)
+
+var UpdateCmd = &cobra.Command{
+	Use:   "update [nightly|stable|all]",
+	Short: "Install newer nightly and stable releases",
+	Long: `Check for a newer nightly and stable release and install only what is new.
+'all' (the default) only updates the channels that have something installed;
+naming a channel updates it either way.
+
+Old nightlies are removed past the rollback limit of config.json. With
+--clean, the stable version that was replaced is removed too. When the
+version in use belongs to an updated channel, nea switches to the new one
+unless --no-switch is given.
+
+--quiet only reports changes and errors, with a timestamp, which is what
+the scheduled update ('nea schedule') runs. Nothing is ever asked, so it
+is safe to run without a terminal.`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"nightly", "stable", "all"},
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		utils.Setup()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		channel := "all"
+		if len(args) == 1 {
+			channel = args[0]
+		}
+		quietOutput = updateQuiet
+		if updateQuiet {
+			githubClient.Notice = func(message string) {
+				fmt.Fprintln(os.Stderr, logPrefix()+"Note: "+message)
+			}
+		}
+		if err := update(channel); err != nil {
+			fmt.Println(logPrefix()+"Error:", err)
+		}
+	},
+}
+
+func init() {
+	UpdateCmd.Flags().BoolVar(&updateNoSwitch, "no-switch", false, "don't switch to the updated version")
+	UpdateCmd.Flags().BoolVar(&updateClean, "clean", false, "remove the stable version that was replaced")
+	UpdateCmd.Flags().BoolVarP(&updateQuiet, "quiet", "q", false, "only report changes and errors, with a timestamp (for scheduled runs)")
+}
+
+// channelUpdate is the summary line of one channel
+type channelUpdate struct {
+	channel  string
+	from     string
+	to       string
+	switched bool
+	err      error
+}
+
+func update(channel string) error {
+	if channel != "nightly" && channel != "stable" && channel != "all" {
+		return fmt.Errorf("invalid channel '%s', expected nightly, stable or all", channel)
+	}
+	current, _ := utils.DetermineCurrentVersion()
+
+	var updates []channelUpdate
+	if channel == "nightly" || channel == "all" {
+		if result, ok := updateNightly(current, channel == "nightly"); ok {
+			updates = append(updates, result)
+		}
+	}
+	if channel == "stable" || channel == "all" {
+		if result, ok := updateStable(current, channel == "stable"); ok {
+			updates = append(updates, result)
+		}
+	}
+	if len(updates) == 0 {
+		if !updateQuiet {
+			fmt.Println("Nothing installed to update, use 'nea install' first.")
+		}
+		return nil
+	}
+
+	prefix := "  "
+	if updateQuiet {
+		prefix = logPrefix()
+	} else {
+		fmt.Println()
+	}
+	failed := 0
+	for _, result := range updates {
+		switch {
+		case result.err != nil:
+			color.Red("%s%-8s %v", prefix, result.channel, result.err)
+			failed++
+		case result.from == result.to:
+			if !updateQuiet {
+				fmt.Printf("%s%-8s %s (up to date)\n", prefix, result.channel, result.to)
+			}
+		default:
+			line := fmt.Sprintf("%s%-8s %s → %s", prefix, result.channel, result.from, result.to)
+			if result.switched {
+				line += " (now in use)"
+			}
+			color.Green(line)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d channel(s) failed to update", failed)
+	}
+	return nil
+}
+
+// updateNightly installs the latest nightly when it is newer than the
+// installed ones. It reports nothing when no nightly is installed and the
+// channel wasn't asked for.
+func updateNightly(current string, requested bool) (channelUpdate, bool) {
+	result := channelUpdate{channel: "nightly", from: "none"}
+	installed, err := utils.ReadVersionsInfo()
+	if err != nil {
+		result.err = err
+		return result, true
+	}
+	if len(installed) == 0 && !requested {
+		return result, false
+	}
+	if len(installed) > 0 {
+		result.from = nightlyLabel(installed[0].CreatedAt)
+	}
+
+	latest, err := fetchLatestNightlyRelease()
+	if err != nil {
+		result.err = fmt.Errorf("failed to fetch release information: %w", err)
+		return result, true
+	}
+	result.to = nightlyLabel(latest.CreatedAt)
+	if len(installed) > 0 && (installed[0].NodeID == latest.NodeID || latest.CreatedAt <= installed[0].CreatedAt) {
+		result.to = result.from
+		return result, true
+	}
+
+	if _, _, err = installNightlyBuild(installAppImage); err != nil {
+		result.err = err
+		return result, true
+	}
+	// The current version is the directory of the nightly in use. Following
+	// the newest one switches, a rolled back one stays where it is.
+	if len(installed) > 0 && current == filepath.Base(installed[0].Directory) && !updateNoSwitch {
+		if result.err = useVersion("nightly", nil); result.err == nil {
+			result.switched = true
+		}
+	}
+	return result, true
+}
+
+// updateStable installs the latest stable release when it is newer than the
+// newest installed stable
+func updateStable(current string, requested bool) (channelUpdate, bool) {
+	result := channelUpdate{channel: "stable", from: "none"}
+	installed, _ := utils.GetLocalStableVersions()
+	if len(installed) == 0 && !requested {
+		return result, false
+	}
+	if len(installed) > 0 {
+		result.from = installed[0]
+	}
+
+	latest, err := utils.FetchLatestStable(stableSource)
+	if err != nil {
+		result.err = fmt.Errorf("failed to fetch latest stable version: %w", err)
+		return result, true
+	}
+	result.to = latest
+	if len(installed) > 0 && semver.Compare("v"+latest, "v"+installed[0]) <= 0 {
+		result.to = result.from
+		return result, true
+	}
+
+	if _, err = installStable(latest); err != nil {
+		result.err = err
+		return result, true
+	}
+	previous := result.from
+	if current == previous && !updateNoSwitch {
+		if result.err = useVersion(latest, nil); result.err == nil {
+			result.switched = true
+			current = latest
+		}
+	}
+
+	// Never remove the version that is still in use
+	if updateClean && previous != "none" && previous != current {
+		if err = cleanSpecificStable(previous); err != nil {
+			color.Yellow(logPrefix()+"Warning: failed to remove %s: %v", previous, err)
+		}
+	}
+	return result, true
+}
+
+// nightlyLabel shortens a nightly's creation time for the summary
+func nightlyLabel(createdAt string) string {
+	t, err := time.Parse(time.RFC3339, createdAt)
+	if err != nil {
+		return createdAt
+	}
+	return strings.TrimSuffix(t.Format("2006-01-02 15:04"), " 00:00")
+}
@@ -5,17 +5,26 @@ import (
 	"nvm_manager_go/utils"
 	"os"
 	"path/filepath"
-	"strings"
 
-	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
 
 var UseCmd = &cobra.Command{
-	Use:   "use",
+	Use:   "use [version]",
 	Short: "Use a Neovim version",
+	Long: `Use a Neovim version: 'nightly', 'stable' (or 'latest'), 'previous-stable',
+a version number, a range such as 0.10, ^0.10 or ">=0.9 <0.11" (the newest
+installed match), the commit of an installed nightly, a linked version or
+an alias (see 'nea alias'). Without one, on a terminal, the version picker
+opens (see 'nea pick').`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) == 0 {
+			if interactive() {
+				if err := pick(); err != nil {
+					fmt.Println("Error:", err)
+				}
+				return
+			}
 			fmt.Println("Error: You must specify 'nightly', a version number, or 'stable'")
 			return
 		}
@@ -68,45 +77,32 @@ func useVersion(version string, optionalDir *string) error {
 		return fmt.Errorf("failed to create bin directory: %w", err)
 	}
 
-	// Handle stable version differently
-	if version == "stable" {
-		// First check local versions
-		localVersions, localErr := utils.GetLocalStableVersions()
-		if localErr != nil {
-			return fmt.Errorf("failed to get local versions: %w", localErr)
-		}
+	version = utils.ExpandAlias(version)
 
-		if len(localVersions) == 0 {
-			return fmt.Errorf("no stable versions installed. Run 'nvm install stable' first")
-		}
-
-		// Use the latest local version
-		version = localVersions[0]
+	// Linked versions point at binaries nea doesn't manage, nothing to resolve
+	if linked, ok := utils.FindLinkedVersion(version); ok {
+		return linkBinary(linked.Binary, symlinkPath)
+	}
 
-		// Check if newer version is available online
-		latestOnline, onlineErr := utils.FetchLatestStable()
-		if onlineErr == nil && version != latestOnline { // Only show warning if we can fetch latest version
-			yellow := color.New(color.FgYellow).SprintFunc()
-			fmt.Printf("%s\n", yellow(fmt.Sprintf("Note: A newer version (%s) is available. Run 'nvm install stable' to get it.", latestOnline)))
+	// A nightly can be named by the commit it was built from
+	if _, nightly, ok := utils.FindNightlyByCommit(version); ok {
+		neovimBinary, err := nightlyBinary(nightly)
+		if err != nil {
+			return err
 		}
-	} else if version != "nightly" {
-		// For specific versions, resolve version number
-		resolvedVersion, resolveErr := utils.ResolveVersion(version)
+		return linkBinary(neovimBinary, symlinkPath)
+	}
+
+	// Keywords are the published releases, ranges resolve among the
+	// installed versions
+	if version != "nightly" {
+		resolvedVersion, resolveErr := utils.ResolveInstalledVersion(stableSource, version, notef)
 		if resolveErr != nil {
 			return resolveErr
 		}
 		version = resolvedVersion
 	}
 
-	// Get the correct archive filename for the architecture
-	archiveFilename, err := getArchiveFilename(version)
-	if err != nil {
-		return fmt.Errorf("failed to determine archive filename: %w", err)
-	}
-
-	// Extract the directory name from the archive filename
-	dirName := strings.TrimSuffix(archiveFilename, ".tar.gz")
-
 	var neovimBinary string
 	if version == "nightly" {
 		if optionalDir != nil {
@@ -116,43 +112,46 @@ func useVersion(version string, optionalDir *string) error {
 			if readErr != nil || len(versions) == 0 {
 				return fmt.Errorf("no nightly versions installed")
 			}
-			
-			// Check if the directory exists directly
-			versionPath := versions[0].Directory
-			nvimBinPath := filepath.Join(versionPath, dirName, "bin/nvim")
-			
-			// Check if the binary exists at the expected path
-			if _, err := os.Stat(nvimBinPath); err == nil {
-				neovimBinary = nvimBinPath
-			} else {
-				// Handle case where directory structure might be different
-				// This could happen with the new timestamp-based directories
-				entries, err := os.ReadDir(versionPath)
-				if err != nil {
-					return fmt.Errorf("failed to read nightly directory: %w", err)
-				}
-				
-				// Look for nvim directory within the version directory
-				for _, entry := range entries {
-					if entry.IsDir() && strings.Contains(entry.Name(), "nvim") {
-						possibleBin := filepath.Join(versionPath, entry.Name(), "bin/nvim")
-						if _, err := os.Stat(possibleBin); err == nil {
-							neovimBinary = possibleBin
-							break
-						}
-					}
-				}
-				
-				// If we still don't have a binary path, return an error
-				if neovimBinary == "" {
-					return fmt.Errorf("neovim binary not found in %s", versionPath)
-				}
+			if neovimBinary, err = nightlyBinary(versions[0]); err != nil {
+				return err
 			}
 		}
-	} else {
-		neovimBinary = filepath.Join(targetDirStable, version, dirName, "bin", "nvim")
+	} else if neovimBinary, err = stableBinary(version); err != nil {
+		return err
+	}
+
+	return linkBinary(neovimBinary, symlinkPath)
+}
+
+// nightlyBinary returns the binary recorded for a nightly. Entries written
+// before binaries were recorded are searched for instead.
+func nightlyBinary(version utils.VersionInfo) (string, error) {
+	if version.Binary != "" {
+		return version.Binary, nil
+	}
+	binary, err := findExtractedBinary(version.Directory)
+	if err != nil {
+		return "", fmt.Errorf("neovim binary not found in %s", version.Directory)
+	}
+	return binary, nil
+}
+
+// stableBinary returns the binary of an installed stable version, using the
+// registry when it knows about it and the stable directory otherwise
+func stableBinary(version string) (string, error) {
+	if entry, ok := utils.FindStableVersion(version); ok && entry.Binary != "" {
+		return entry.Binary, nil
 	}
+	versionDir := filepath.Join(targetDirStable, version)
+	binary, err := findExtractedBinary(versionDir)
+	if err != nil {
+		return "", fmt.Errorf("version %s is not installed", version)
+	}
+	return binary, nil
+}
 
+// linkBinary points the symlink at the given neovim binary
+func linkBinary(neovimBinary, symlinkPath string) error {
 	// Verify the binary exists
 	if _, err := os.Stat(neovimBinary); err != nil {
 		return fmt.Errorf("neovim binary not found at %s: %w", neovimBinary, err)
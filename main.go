@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"nvm_manager_go/commands"
+	"nvm_manager_go/utils"
 	"os"
 	"path/filepath"
 
@@ -12,15 +13,15 @@ import (
 var (
 	homeDir          = os.Getenv("HOME")
 	appDir           = filepath.Join(homeDir, ".local", "share", "neoManager")
-	neovimURL        = "https://github.com/neovim/neovim/releases/download/nightly/nvim-macos.tar.gz"
 	targetNightly    = filepath.Join(homeDir, ".local", "share", "neoManager", "nightly")
 	stableBaseURL    = "https://github.com/neovim/neovim/releases/download/v"
 	targetDirNightly = filepath.Join(homeDir, ".local", "share", "neoManager", "nightly")
 	targetDirStable  = filepath.Join(homeDir, ".local", "share", "neoManager", "stable")
-	tagsURL          = "https://api.github.com/repos/neovim/neovim/tags"
-	tagsNightlyURL   = "https://api.github.com/repos/neovim/neovim/releases/tags/nightly"
 )
 
+// version is set at build time with -ldflags "-X main.version=..."
+var version = "dev"
+
 type VersionInfo struct {
 	NodeID       string `json:"node_id"`
 	CreatedAt    string `json:"created_at"`
@@ -30,15 +31,59 @@ type VersionInfo struct {
 
 func main() {
 	rootCmd := &cobra.Command{
-		Use:   "nvm",
-		Short: "Neovim Version Manager (Go)",
+		Use:     "nea",
+		Short:   "Neovim Version Manager (Go)",
+		Version: version,
+	}
+
+	config, _ := utils.ReadConfig()
+	httpClient, err := utils.NewHTTPClient(config.Network, version)
+	if err != nil {
+		fmt.Println("Error in config:", err)
+		os.Exit(1)
 	}
+	utils.SetHTTPClient(httpClient)
+
+	client := utils.NewGitHubClient(utils.GitHubToken())
+	client.HTTPClient = httpClient
+	client.Cache = utils.NewDiskCache()
+	commands.SetGitHubClient(client)
+
+	var offline bool
+	rootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "work only from cached metadata and local state (or set NEA_OFFLINE=1)")
+	cobra.OnInitialize(func() {
+		client.Offline = offline || utils.OfflineFromEnv()
+
+		stable, nightly, err := utils.ReleaseSourcesFromConfig(client)
+		if err != nil {
+			fmt.Println("Error in config:", err)
+			os.Exit(1)
+		}
+		commands.SetReleaseSources(stable, nightly)
+	})
 
 	rootCmd.AddCommand(commands.InstallCmd)
 	rootCmd.AddCommand(commands.UseCmd)
 	rootCmd.AddCommand(commands.ListCmd)
 	rootCmd.AddCommand(commands.RollbackCmd)
 	rootCmd.AddCommand(commands.CleanCmd)
+	rootCmd.AddCommand(commands.LinkCmd)
+	rootCmd.AddCommand(commands.ImportCmd)
+	rootCmd.AddCommand(commands.CacheCmd)
+	rootCmd.AddCommand(commands.BundleCmd)
+	rootCmd.AddCommand(commands.SyncCmd)
+	rootCmd.AddCommand(commands.LockCmd)
+	rootCmd.AddCommand(commands.UpdateCmd)
+	rootCmd.AddCommand(commands.ScheduleCmd)
+	rootCmd.AddCommand(commands.DoctorCmd)
+	rootCmd.AddCommand(commands.RepairCmd)
+	rootCmd.AddCommand(commands.InitCmd)
+	rootCmd.AddCommand(commands.EnvCmd)
+	rootCmd.AddCommand(commands.PickCmd)
+	rootCmd.AddCommand(commands.ChangelogCmd)
+	rootCmd.AddCommand(commands.AliasCmd)
+	rootCmd.AddCommand(commands.ExecCmd)
+	rootCmd.AddCommand(commands.StatusCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// aliasesPath holds the aliases of 'nea alias', by name
+var aliasesPath = filepath.Join(appDir, "aliases.json")
+
+// ReadAliases returns the user-defined aliases, none when there is no file
+func ReadAliases() (map[string]string, error) {
+	aliases := make(map[string]string)
+	data, err := os.ReadFile(aliasesPath)
+	if os.IsNotExist(err) {
+		return aliases, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read aliases: %w", err)
+	}
+	if err = json.Unmarshal(data, &aliases); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", aliasesPath, err)
+	}
+	return aliases, nil
+}
+
+// WriteAliases replaces every alias
+func WriteAliases(aliases map[string]string) error {
+	data, err := json.MarshalIndent(aliases, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal aliases: %w", err)
+	}
+	if err = os.WriteFile(aliasesPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write aliases: %w", err)
+	}
+	return nil
+}
+
+// ExpandAlias returns what an alias stands for, anything else unchanged.
+// Aliases can't point at other aliases, so one lookup is enough. Versions
+// are never looked up, an alias can't override one.
+func ExpandAlias(version string) string {
+	if IsVersion(version) || IsVersionRange(version) {
+		return version
+	}
+	aliases, err := ReadAliases()
+	if err != nil {
+		return version
+	}
+	if target, ok := aliases[version]; ok {
+		return target
+	}
+	return version
+}
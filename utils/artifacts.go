@@ -0,0 +1,281 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultArtifactCacheLimitMB is the size the artifact cache is pruned to
+// when config.json doesn't say otherwise
+const DefaultArtifactCacheLimitMB = 2048
+
+var (
+	artifactCacheDir  = filepath.Join(cacheDir, "artifacts")
+	artifactIndexPath = filepath.Join(artifactCacheDir, "index.json")
+	artifactMu        sync.Mutex
+)
+
+// Artifact is a downloaded file kept in the cache. Files are stored by their
+// SHA-256, so the same archive reached through several URLs is kept once.
+type Artifact struct {
+	URL      string    `json:"url"`
+	SHA256   string    `json:"sha256"`
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	StoredAt time.Time `json:"stored_at"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+// Path is where the artifact's content is stored
+func (a Artifact) Path() string {
+	return filepath.Join(artifactCacheDir, a.SHA256)
+}
+
+// ArtifactCacheLimit returns the configured cache size limit in bytes, 0
+// meaning unlimited
+func ArtifactCacheLimit() int64 {
+	limit := DefaultArtifactCacheLimitMB
+	if config, err := ReadConfig(); err == nil && config.ArtifactCacheLimitMB != 0 {
+		limit = config.ArtifactCacheLimitMB
+	}
+	if limit < 0 {
+		return 0
+	}
+	return int64(limit) << 20
+}
+
+// ListArtifacts returns the cached artifacts, most recently used first
+func ListArtifacts() ([]Artifact, error) {
+	artifactMu.Lock()
+	defer artifactMu.Unlock()
+	artifacts, err := readArtifactIndex()
+	if err != nil {
+		return nil, err
+	}
+	sortArtifacts(artifacts)
+	return artifacts, nil
+}
+
+// LookupArtifact returns the cached copy of url. When checksum is given any
+// artifact with that content will do, wherever it was downloaded from.
+// Otherwise the most recent download of url is used.
+func LookupArtifact(url, checksum string) (Artifact, bool) {
+	artifactMu.Lock()
+	defer artifactMu.Unlock()
+	artifacts, err := readArtifactIndex()
+	if err != nil {
+		return Artifact{}, false
+	}
+	sortArtifacts(artifacts)
+
+	for i, artifact := range artifacts {
+		if checksum != "" && !strings.EqualFold(artifact.SHA256, checksum) || checksum == "" && artifact.URL != url {
+			continue
+		}
+		if _, err := os.Stat(artifact.Path()); err != nil {
+			continue
+		}
+		artifacts[i].LastUsed = time.Now()
+		writeArtifactIndex(artifacts)
+		return artifacts[i], true
+	}
+	return Artifact{}, false
+}
+
+// StoreArtifact copies a downloaded file into the cache under its SHA-256
+// and prunes the cache back to its size limit
+func StoreArtifact(url, filePath string) (Artifact, error) {
+	sum, size, err := hashFile(filePath)
+	if err != nil {
+		return Artifact{}, err
+	}
+	now := time.Now()
+	artifact := Artifact{URL: url, SHA256: sum, Name: filepath.Base(filePath), Size: size, StoredAt: now, LastUsed: now}
+
+	artifactMu.Lock()
+	defer artifactMu.Unlock()
+	if err = os.MkdirAll(artifactCacheDir, 0o755); err != nil {
+		return Artifact{}, fmt.Errorf("failed to create artifact cache: %w", err)
+	}
+	if _, err = os.Stat(artifact.Path()); err != nil {
+		if err = linkOrCopy(filePath, artifact.Path()); err != nil {
+			return Artifact{}, fmt.Errorf("failed to cache %s: %w", artifact.Name, err)
+		}
+	}
+
+	artifacts, err := readArtifactIndex()
+	if err != nil {
+		return Artifact{}, err
+	}
+	artifacts = filterArtifacts(artifacts, func(a Artifact) bool {
+		return a.URL != url || a.SHA256 != sum
+	})
+	artifacts = append(artifacts, artifact)
+	artifacts, _ = pruneArtifacts(artifacts, ArtifactCacheLimit(), 0)
+	return artifact, writeArtifactIndex(artifacts)
+}
+
+// RestoreArtifact places a cached artifact at filePath
+func RestoreArtifact(artifact Artifact, filePath string) error {
+	if err := linkOrCopy(artifact.Path(), filePath); err != nil {
+		return fmt.Errorf("failed to restore %s from cache: %w", artifact.Name, err)
+	}
+	return nil
+}
+
+// PruneArtifacts drops artifacts unused for longer than maxAge (when not
+// zero), then the least recently used ones until the cache fits in maxSize
+// bytes (when not zero). It returns what was removed.
+func PruneArtifacts(maxSize int64, maxAge time.Duration) ([]Artifact, error) {
+	artifactMu.Lock()
+	defer artifactMu.Unlock()
+	artifacts, err := readArtifactIndex()
+	if err != nil {
+		return nil, err
+	}
+	kept, removed := pruneArtifacts(artifacts, maxSize, maxAge)
+	return removed, writeArtifactIndex(kept)
+}
+
+// ClearCache removes every cached artifact and all cached metadata
+func ClearCache() error {
+	artifactMu.Lock()
+	defer artifactMu.Unlock()
+	if err := os.RemoveAll(cacheDir); err != nil {
+		return fmt.Errorf("failed to clear cache: %w", err)
+	}
+	return nil
+}
+
+// CacheDir is where nea keeps cached metadata and downloads
+func CacheDir() string {
+	return cacheDir
+}
+
+// pruneArtifacts removes entries whose file is gone, those unused for longer
+// than maxAge and then the least recently used ones over maxSize. Files
+// nothing refers to anymore are deleted, including stray ones.
+func pruneArtifacts(artifacts []Artifact, maxSize int64, maxAge time.Duration) (kept, removed []Artifact) {
+	sortArtifacts(artifacts)
+
+	var total int64
+	sizes := make(map[string]int64)
+	for _, artifact := range artifacts {
+		if _, err := os.Stat(artifact.Path()); err != nil {
+			continue
+		}
+		if maxAge > 0 && time.Since(artifact.LastUsed) > maxAge {
+			removed = append(removed, artifact)
+			continue
+		}
+		// Several URLs can share one file, it only counts once
+		if _, counted := sizes[artifact.SHA256]; !counted && maxSize > 0 && total+artifact.Size > maxSize {
+			removed = append(removed, artifact)
+			continue
+		}
+		if _, counted := sizes[artifact.SHA256]; !counted {
+			total += artifact.Size
+		}
+		sizes[artifact.SHA256] = artifact.Size
+		kept = append(kept, artifact)
+	}
+
+	entries, _ := os.ReadDir(artifactCacheDir)
+	for _, entry := range entries {
+		if _, used := sizes[entry.Name()]; !used && entry.Name() != filepath.Base(artifactIndexPath) {
+			os.Remove(filepath.Join(artifactCacheDir, entry.Name()))
+		}
+	}
+	return kept, removed
+}
+
+func readArtifactIndex() ([]Artifact, error) {
+	data, err := os.ReadFile(artifactIndexPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact cache index: %w", err)
+	}
+	var artifacts []Artifact
+	if err = json.Unmarshal(data, &artifacts); err != nil {
+		return nil, fmt.Errorf("failed to parse artifact cache index: %w", err)
+	}
+	return artifacts, nil
+}
+
+func writeArtifactIndex(artifacts []Artifact) error {
+	data, err := json.MarshalIndent(artifacts, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize artifact cache index: %w", err)
+	}
+	if err = os.MkdirAll(artifactCacheDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create artifact cache: %w", err)
+	}
+	if err = os.WriteFile(artifactIndexPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write artifact cache index: %w", err)
+	}
+	return nil
+}
+
+func sortArtifacts(artifacts []Artifact) {
+	sort.SliceStable(artifacts, func(i, j int) bool {
+		return artifacts[i].LastUsed.After(artifacts[j].LastUsed)
+	})
+}
+
+func filterArtifacts(artifacts []Artifact, keep func(Artifact) bool) []Artifact {
+	var kept []Artifact
+	for _, artifact := range artifacts {
+		if keep(artifact) {
+			kept = append(kept, artifact)
+		}
+	}
+	return kept
+}
+
+// FileSHA256 returns the hex encoded SHA-256 of a file
+func FileSHA256(filePath string) (string, error) {
+	sum, _, err := hashFile(filePath)
+	return sum, err
+}
+
+// hashFile returns a file's hex encoded SHA-256 and its size
+func hashFile(filePath string) (string, int64, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to open %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to hash %s: %w", filePath, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+// linkOrCopy hard links src to dst, copying when they are on different
+// filesystems. The copy goes through a temporary file so a partial one is
+// never left behind.
+func linkOrCopy(src, dst string) error {
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	tmp := dst + ".tmp"
+	if err := copyFile(src, tmp, 0o644); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
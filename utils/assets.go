@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// Asset formats nea knows how to install
+const (
+	FormatTarball  = ".tar.gz"
+	FormatAppImage = ".appimage"
+)
+
+// ReleaseAsset is a downloadable file attached to a GitHub release
+type ReleaseAsset struct {
+	Name               string `json:"name"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+	Size               int64  `json:"size"`
+	// Digest is "sha256:<hex>" when the source knows the asset's checksum
+	Digest string `json:"digest,omitempty"`
+}
+
+// Tokens used in neovim's asset names, e.g. nvim-macos-arm64.tar.gz or
+// nvim-linux64.tar.gz (x86_64 Linux before 0.10.4)
+var (
+	assetOSTokens = map[string][]string{
+		"darwin": {"macos", "osx"},
+		"linux":  {"linux"},
+	}
+	assetArchTokens = map[string][]string{
+		"amd64": {"x86_64", "64"},
+		"arm64": {"arm64", "aarch64"},
+	}
+)
+
+// ResolveAsset picks the asset of the given format that best fits the platform.
+// An asset built for the exact architecture wins over a universal one.
+func ResolveAsset(assets []ReleaseAsset, platform Platform, format string) (ReleaseAsset, error) {
+	var best ReleaseAsset
+	bestScore := 0
+	for _, asset := range assets {
+		if !strings.HasSuffix(asset.Name, format) {
+			continue
+		}
+		if score := scoreAsset(asset.Name, platform, format); score > bestScore {
+			best, bestScore = asset, score
+		}
+	}
+
+	if bestScore == 0 {
+		return ReleaseAsset{}, fmt.Errorf("%w: no %s build for %s/%s in this release", ErrNoCompatibleBuild, format, platform.OS, platform.Arch)
+	}
+	return best, nil
+}
+
+// ResolveCompatibleAsset resolves the asset like ResolveAsset and also makes
+// sure the platform's libc can run it, explaining why when it can't.
+func ResolveCompatibleAsset(assets []ReleaseAsset, platform Platform, format, version string) (ReleaseAsset, error) {
+	asset, err := ResolveAsset(assets, platform, format)
+	if err != nil {
+		if platform.Libc == "musl" {
+			return ReleaseAsset{}, fmt.Errorf("%w: this system uses musl libc but the official Neovim builds are linked against glibc", ErrNoCompatibleBuild)
+		}
+		return ReleaseAsset{}, err
+	}
+
+	if platform.Libc == "glibc" && platform.GlibcVersion != "" && !strings.Contains(asset.Name, "musl") {
+		required := minimumGlibc(version)
+		if semver.Compare("v"+platform.GlibcVersion, "v"+required) < 0 {
+			return ReleaseAsset{}, fmt.Errorf("%w: %s needs glibc %s or newer, this system has glibc %s",
+				ErrNoCompatibleBuild, asset.Name, required, platform.GlibcVersion)
+		}
+	}
+	return asset, nil
+}
+
+// scoreAsset returns 2 for an exact architecture match, 1 for a universal
+// build and 0 when the asset can't run on the platform
+func scoreAsset(name string, platform Platform, format string) int {
+	name = strings.TrimSuffix(strings.TrimPrefix(name, "nvim-"), format)
+
+	rest, found := "", false
+	for _, token := range assetOSTokens[platform.OS] {
+		if strings.HasPrefix(name, token) {
+			rest, found = strings.TrimPrefix(strings.TrimPrefix(name, token), "-"), true
+			break
+		}
+	}
+	if !found {
+		return 0
+	}
+
+	// Official builds are linked against glibc unless the name says otherwise
+	rest, isMusl := strings.CutSuffix(rest, "-musl")
+	if isMusl != (platform.Libc == "musl") {
+		return 0
+	}
+
+	if rest == "" {
+		return 1
+	}
+	for _, token := range assetArchTokens[platform.Arch] {
+		if rest == token {
+			return 2
+		}
+	}
+	return 0
+}
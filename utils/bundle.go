@@ -0,0 +1,247 @@
+package utils
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// bundleFormat is bumped whenever the bundle layout changes incompatibly
+const bundleFormat = 1
+
+const bundleManifestName = "manifest.json"
+
+// BundleManifest describes the versions packed in a bundle. It is the first
+// file of the tar, each version's tree follows as a .tar.gz.
+type BundleManifest struct {
+	Format    int           `json:"format"`
+	CreatedAt string        `json:"created_at"`
+	Platform  Platform      `json:"platform"`
+	Entries   []BundleEntry `json:"entries"`
+}
+
+// BundleEntry is one packed version. Its registry entry keeps the nightly
+// metadata as is, but Directory is dropped and Binary is relative to it.
+type BundleEntry struct {
+	Version VersionInfo `json:"version"`
+	Archive string      `json:"archive"`
+	SHA256  string      `json:"sha256"`
+	Size    int64       `json:"size"`
+}
+
+// NewBundleManifest starts a manifest for this machine
+func NewBundleManifest(createdAt string) BundleManifest {
+	return BundleManifest{Format: bundleFormat, CreatedAt: createdAt, Platform: CurrentPlatform()}
+}
+
+// AddArchive records a packed version, hashing its archive
+func (m *BundleManifest) AddArchive(version VersionInfo, archivePath string) error {
+	sum, size, err := hashFile(archivePath)
+	if err != nil {
+		return err
+	}
+	m.Entries = append(m.Entries, BundleEntry{
+		Version: version,
+		Archive: "versions/" + filepath.Base(archivePath),
+		SHA256:  sum,
+		Size:    size,
+	})
+	return nil
+}
+
+// WriteBundle writes the manifest and the archives it lists, found in
+// archiveDir, to a tar file
+func WriteBundle(path string, manifest BundleManifest, archiveDir string) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+	defer out.Close()
+	tarWriter := tar.NewWriter(out)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize bundle manifest: %w", err)
+	}
+	header := &tar.Header{Name: bundleManifestName, Mode: 0o644, Size: int64(len(data)), ModTime: time.Now()}
+	if err = tarWriter.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if _, err = tarWriter.Write(data); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	for _, entry := range manifest.Entries {
+		if err = addFileToTar(tarWriter, filepath.Join(archiveDir, filepath.Base(entry.Archive)), entry.Archive); err != nil {
+			return err
+		}
+	}
+	if err = tarWriter.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return out.Close()
+}
+
+// ReadBundle unpacks a bundle's archives into dir, checking each against the
+// manifest's checksum, and returns the manifest
+func ReadBundle(path, dir string) (BundleManifest, error) {
+	var manifest BundleManifest
+	file, err := os.Open(path)
+	if err != nil {
+		return manifest, fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer file.Close()
+	tarReader := tar.NewReader(file)
+
+	header, err := tarReader.Next()
+	if err != nil || header.Name != bundleManifestName {
+		return manifest, fmt.Errorf("%s is not a nea bundle", path)
+	}
+	if err = json.NewDecoder(tarReader).Decode(&manifest); err != nil {
+		return manifest, fmt.Errorf("failed to parse bundle manifest: %w", err)
+	}
+	if manifest.Format != bundleFormat {
+		return manifest, fmt.Errorf("unsupported bundle format %d, expected %d", manifest.Format, bundleFormat)
+	}
+
+	expected := make(map[string]BundleEntry)
+	for _, entry := range manifest.Entries {
+		expected[entry.Archive] = entry
+	}
+	for {
+		header, err = tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return manifest, fmt.Errorf("failed to read bundle: %w", err)
+		}
+		entry, ok := expected[header.Name]
+		if !ok {
+			return manifest, fmt.Errorf("bundle contains %s which its manifest doesn't list", header.Name)
+		}
+
+		// Only names from the manifest are used, and only their base name
+		target := filepath.Join(dir, filepath.Base(entry.Archive))
+		if err = writeFileFrom(tarReader, target); err != nil {
+			return manifest, err
+		}
+		if err = VerifySHA256(target, entry.SHA256); err != nil {
+			return manifest, err
+		}
+		delete(expected, header.Name)
+	}
+	for name := range expected {
+		return manifest, fmt.Errorf("bundle is missing %s", name)
+	}
+	return manifest, nil
+}
+
+// CreateTarGz packs the content of srcDir into a gzipped tar, keeping file
+// modes and symlinks, so ExtractTarGz restores it as is
+func CreateTarGz(srcDir, dst string) error {
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer out.Close()
+	gzipWriter := gzip.NewWriter(out)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	err = filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return fmt.Errorf("failed to read symlink %s: %w", path, err)
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return fmt.Errorf("failed to archive %s: %w", path, err)
+		}
+		header.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err = tarWriter.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to archive %s: %w", path, err)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", path, err)
+		}
+		defer file.Close()
+		if _, err = io.Copy(tarWriter, file); err != nil {
+			return fmt.Errorf("failed to archive %s: %w", path, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = tarWriter.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err = gzipWriter.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return out.Close()
+}
+
+func addFileToTar(tarWriter *tar.Writer, path, name string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	header := &tar.Header{Name: name, Mode: 0o644, Size: info.Size(), ModTime: info.ModTime()}
+	if err = tarWriter.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if _, err = io.Copy(tarWriter, file); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return nil
+}
+
+func writeFileFrom(reader io.Reader, path string) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if _, err = io.Copy(out, reader); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return out.Close()
+}
+
+// isWithin reports whether path is dir or inside it
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var (
+	cacheDir         = filepath.Join(appDir, "cache")
+	metadataCacheDir = filepath.Join(cacheDir, "metadata")
+)
+
+// DiskCache is a ResponseCache persisted under the app's cache directory,
+// one JSON file per URL, so release metadata survives between runs
+type DiskCache struct {
+	Dir string
+}
+
+// diskCacheEntry is one cached response. Body is base64 encoded since
+// mirror listings and checksum files aren't JSON; entries written when it
+// was raw JSON no longer decode and are fetched again.
+type diskCacheEntry struct {
+	URL      string    `json:"url"`
+	ETag     string    `json:"etag,omitempty"`
+	Link     string    `json:"link,omitempty"`
+	StoredAt time.Time `json:"stored_at"`
+	Body     []byte    `json:"body"`
+}
+
+func NewDiskCache() *DiskCache {
+	return &DiskCache{Dir: metadataCacheDir}
+}
+
+func (d *DiskCache) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(d.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (d *DiskCache) Load(url string) (CachedResponse, bool) {
+	data, err := os.ReadFile(d.path(url))
+	if err != nil {
+		return CachedResponse{}, false
+	}
+	var entry diskCacheEntry
+	if err = json.Unmarshal(data, &entry); err != nil || entry.URL != url {
+		return CachedResponse{}, false
+	}
+	return CachedResponse{ETag: entry.ETag, Link: entry.Link, Body: entry.Body, StoredAt: entry.StoredAt}, true
+}
+
+// Store writes the entry, failures only cost a request next time so they
+// are ignored
+func (d *DiskCache) Store(url string, response CachedResponse) {
+	data, err := json.Marshal(diskCacheEntry{
+		URL:      url,
+		ETag:     response.ETag,
+		Link:     response.Link,
+		StoredAt: response.StoredAt,
+		Body:     response.Body,
+	})
+	if err != nil {
+		return
+	}
+	if err = os.MkdirAll(d.Dir, 0o755); err != nil {
+		return
+	}
+	os.WriteFile(d.path(url), data, 0o644)
+}
+
+// OfflineFromEnv reports whether NEA_OFFLINE asks for offline mode
+func OfflineFromEnv() bool {
+	switch os.Getenv("NEA_OFFLINE") {
+	case "", "0", "false", "no":
+		return false
+	}
+	return true
+}
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiskCacheStoresAnyBody(t *testing.T) {
+	cache := &DiskCache{Dir: t.TempDir()}
+	bodies := map[string]string{
+		"https://mirror.example/":                         `<html><a href="v0.10.4/">v0.10.4/</a></html>`,
+		"https://mirror.example/v0.10.4/shasum.txt":       "0123abcd  nvim-linux-x86_64.tar.gz\n",
+		"https://api.github.com/repos/neovim/neovim/tags": `[{"name":"v0.10.4"}]`,
+	}
+	storedAt := time.Now().UTC().Truncate(time.Second)
+	for url, body := range bodies {
+		cache.Store(url, CachedResponse{ETag: `"etag"`, Body: []byte(body), StoredAt: storedAt})
+	}
+	for url, body := range bodies {
+		cached, ok := cache.Load(url)
+		if !ok {
+			t.Errorf("%s was not cached", url)
+			continue
+		}
+		if string(cached.Body) != body || cached.ETag != `"etag"` || !cached.StoredAt.Equal(storedAt) {
+			t.Errorf("%s came back as %+v", url, cached)
+		}
+	}
+}
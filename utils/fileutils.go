@@ -16,20 +16,17 @@ import (
 	"time"
 
 	"github.com/fatih/color"
+	"golang.org/x/mod/semver"
 )
 
 var (
 	homeDir          = os.Getenv("HOME")
 	appDir           = filepath.Join(homeDir, ".local", "share", "neoManager")
 	SymlinkPath      = filepath.Join(appDir, "bin/nvim")
-	configPath       = filepath.Join(appDir, "config.json")
-	neovimURL        = "https://github.com/neovim/neovim/releases/download/nightly/nvim-macos.tar.gz"
+	ConfigPath       = filepath.Join(appDir, "config.json")
 	targetNightly    = filepath.Join(homeDir, ".local", "share", "neoManager", "nightly")
-	StableBaseURL    = "https://github.com/neovim/neovim/releases/download/v"
 	targetDirNightly = filepath.Join(homeDir, ".local", "share", "neoManager", "nightly")
 	targetDirStable  = filepath.Join(homeDir, ".local", "share", "neoManager", "stable")
-	tagsURL          = "https://api.github.com/repos/neovim/neovim/tags"
-	tagsNightlyURL   = "https://api.github.com/repos/neovim/neovim/releases/tags/nightly"
 	versionFilePath  = filepath.Join(targetNightly, "versions_info.json")
 )
 
@@ -38,6 +35,14 @@ type VersionInfo struct {
 	CreatedAt    string `json:"created_at"`
 	Directory    string `json:"directory"`
 	UniqueNumber int    `json:"unique_number"`
+	Kind         string `json:"kind,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Version      string `json:"version,omitempty"`
+	Binary       string `json:"binary,omitempty"`
+	RootDir      string `json:"root_dir,omitempty"`
+	Asset        string `json:"asset,omitempty"`
+	// Build is what 'nvim --version' said when it was installed
+	Build *BuildInfo `json:"build,omitempty"`
 }
 
 // Struct to represent release info
@@ -46,33 +51,26 @@ type Release struct {
 }
 
 type Config struct {
-	RollbackLimit int `json:"rollbackLimit"`
+	RollbackLimit int                     `json:"rollbackLimit"`
+	GitHubToken   string                  `json:"githubToken,omitempty"`
+	Sources       map[string]SourceConfig `json:"sources,omitempty"`
+	Network       NetworkConfig           `json:"network,omitempty"`
+	// ArtifactCacheLimitMB caps the download cache, -1 for no limit
+	ArtifactCacheLimitMB int               `json:"artifactCacheLimitMB,omitempty"`
+	HealthCheck          HealthCheckConfig `json:"healthCheck,omitempty"`
 }
 
-// TODO: add number of releases
-// Fetch stable neovim releases
-func FetchReleases() ([]Release, error) {
-	// 1. Make the HTTP Request
-	resp, err := http.Get(tagsURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch releases: %w", err)
-	}
-	defer resp.Body.Close()
+// DefaultRollbackLimit is how many nightlies are kept when config.json
+// doesn't say
+const DefaultRollbackLimit = 7
 
-	// 2. Read the Response Body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	// 3. Parse JSON
-	var releases []Release
-	err = json.Unmarshal(body, &releases)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+// NightlyLimit returns how many nightlies to keep, the default when
+// rollbackLimit is missing or below 1
+func (c Config) NightlyLimit() int {
+	if c.RollbackLimit < 1 {
+		return DefaultRollbackLimit
 	}
-
-	return releases, nil
+	return c.RollbackLimit
 }
 
 // NOTE: Prod-ready function
@@ -91,6 +89,15 @@ func DetermineCurrentVersion() (string, error) {
 		return "", fmt.Errorf("failed to read symlink target: %w", err)
 	}
 
+	// Linked versions live outside of the app directory
+	if linked, err := ReadLinkedVersions(); err == nil {
+		for _, entry := range linked {
+			if entry.Binary == symlinkTarget {
+				return entry.Name, nil
+			}
+		}
+	}
+
 	parts := strings.Split(symlinkTarget, "/")
 
 	// the main logic
@@ -153,9 +160,13 @@ func SortVersionsDesc(versions []VersionInfo) {
 	})
 }
 
-func ResolveVersion(version string) (string, error) {
+// ResolveVersion resolves a version against the releases of source, for
+// installing it. See ResolveInstalledVersion for versions already installed.
+func ResolveVersion(source ReleaseSource, version string) (string, error) {
+	version = ExpandAlias(version)
+
 	// Validate allowed keywords first
-	validKeywords := []string{"stable", "nightly"}
+	validKeywords := []string{"stable", "nightly", "latest"}
 	versionLower := strings.ToLower(version)
 
 	// Check for misspelled keywords first
@@ -165,9 +176,9 @@ func ResolveVersion(version string) (string, error) {
 		}
 	}
 
-	// Handle "stable" keyword
-	if versionLower == "stable" {
-		latestVersion, err := FetchLatestStable()
+	// Handle "stable" keyword, "latest" is the same
+	if versionLower == "stable" || versionLower == "latest" {
+		latestVersion, err := FetchLatestStable(source)
 		if err != nil {
 			return "", fmt.Errorf("failed to fetch latest stable version: %w", err)
 		}
@@ -179,24 +190,67 @@ func ResolveVersion(version string) (string, error) {
 		return "nightly", nil // Just return nightly, let the commands handle it
 	}
 
+	// "previous-stable" and ranges pick among the published releases
+	if versionLower == "previous-stable" || IsVersionRange(version) {
+		return resolveFromReleases(source, version)
+	}
+
 	// Validate version format (should be like "0.9.5" or "v0.9.5")
 	version = strings.TrimPrefix(version, "v") // Remove 'v' prefix if present
-
-	// Regular expression for semantic versioning (major.minor.patch)
-	versionRegex := regexp.MustCompile(`^[0-9]+\.[0-9]+\.[0-9]+$`)
-	if !versionRegex.MatchString(version) {
-		red := color.New(color.FgRed).SprintFunc()
-		cyan := color.New(color.FgCyan).SprintFunc()
-		return "", fmt.Errorf("%s\nValid formats:\n- %s: Latest nightly build\n- %s: Latest stable version\n- %s: Specific version (e.g., 0.9.5)",
-			red("Invalid version format"),
-			cyan("nightly"),
-			cyan("stable"),
-			cyan("x.y.z"))
+	if !exactVersion.MatchString(version) {
+		return "", invalidVersionError()
 	}
 
 	return version, nil
 }
 
+// Regular expression for semantic versioning (major.minor.patch)
+var exactVersion = regexp.MustCompile(`^[0-9]+\.[0-9]+\.[0-9]+$`)
+
+func invalidVersionError() error {
+	red := color.New(color.FgRed).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+	return fmt.Errorf("%s\nValid formats:\n- %s: Latest nightly build\n- %s or %s: Latest stable version\n- %s: The stable version before it\n- %s: Specific version (e.g., 0.9.5)\n- %s: Newest matching version (e.g., 0.10, ^0.10, \">=0.9 <0.11\")\n- an alias defined with 'nea alias'",
+		red("Invalid version format"),
+		cyan("nightly"),
+		cyan("stable"),
+		cyan("latest"),
+		cyan("previous-stable"),
+		cyan("x.y.z"),
+		cyan("range"))
+}
+
+// resolveFromReleases picks "previous-stable" or the newest match of a range
+// among the stable releases of source
+func resolveFromReleases(source ReleaseSource, version string) (string, error) {
+	tags, err := source.ListTags()
+	if err != nil {
+		return "", fmt.Errorf("failed to list releases: %w", err)
+	}
+	var releases []string
+	for _, tag := range tags {
+		if semver.IsValid(tag.Name) && semver.Prerelease(tag.Name) == "" {
+			releases = append(releases, strings.TrimPrefix(tag.Name, "v"))
+		}
+	}
+	sort.Slice(releases, func(i, j int) bool { return semver.Compare("v"+releases[i], "v"+releases[j]) > 0 })
+
+	if strings.ToLower(version) == "previous-stable" {
+		if len(releases) < 2 {
+			return "", fmt.Errorf("fewer than two stable releases found")
+		}
+		return releases[1], nil
+	}
+	versionRange, err := ParseVersionRange(version)
+	if err != nil {
+		return "", err
+	}
+	if resolved, found := versionRange.Highest(releases); found {
+		return resolved, nil
+	}
+	return "", fmt.Errorf("no stable release matches %s", version)
+}
+
 // Helper function to calculate Levenshtein distance for suggesting corrections
 func levenshtein(a, b string) int {
 	if len(a) == 0 {
@@ -227,44 +281,19 @@ func min(nums ...int) int {
 	return m
 }
 
-func FetchLatestStable() (string, error) {
-	releases, err := FetchReleases()
-	if err != nil {
-		return "", err
-	}
-
-	if len(releases) == 0 {
-		return "", fmt.Errorf("no releases found")
-	}
-
-	// Extract the numeric part of the version name
-	re := regexp.MustCompile("[0-9]+")
-	versionParts := re.FindAllString(releases[0].Name, -1)
-	if len(versionParts) == 0 {
-		return "", fmt.Errorf("failed to extract version number")
-	}
-
-	// Combine the numeric parts to form the version number
-	versionNumber := strings.Join(versionParts, ".")
-
-	return versionNumber, nil
-}
-
 // read nightly versions info
 func ReadVersionsInfo() ([]VersionInfo, error) {
-	versionsFilePath := filepath.Join(targetNightly, "versions_info.json")
-
-	// Read the file contents
-	data, err := os.ReadFile(versionsFilePath)
+	entries, err := ReadRegistry()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read versions info file: %w", err)
+		return nil, err
 	}
 
-	// Parse the JSON
-	var versions []VersionInfo
-	err = json.Unmarshal(data, &versions)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse versions info JSON: %w", err)
+	// Only nightlies take part in rollback numbering
+	versions := make([]VersionInfo, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsNightly() {
+			versions = append(versions, entry)
+		}
 	}
 
 	SortVersionsDesc(versions)
@@ -308,11 +337,14 @@ func CreateTargetDirectory(createdAt string) (string, error) {
 }
 
 func DownloadArchive(url, filePath string) error {
-	resp, err := http.Get(url)
+	resp, err := httpClient.Get(url)
 	if err != nil {
 		return fmt.Errorf("failed to download file: %w", err)
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download file: %s returned %s", url, resp.Status)
+	}
 
 	outFile, err := os.Create(filePath)
 	if err != nil {
@@ -328,17 +360,30 @@ func DownloadArchive(url, filePath string) error {
 	return nil
 }
 
-// Helper to extract a tar.gz archive
-func ExtractTarGz(filePath, targetDir string) error {
+// VerifySHA256 checks a file against an expected hex encoded SHA-256
+func VerifySHA256(filePath, expected string) error {
+	actual, _, err := hashFile(filePath)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", filepath.Base(filePath), expected, actual)
+	}
+	return nil
+}
+
+// Helper to extract a tar.gz archive. It returns the archive's top-level
+// directory so callers can record where the files ended up.
+func ExtractTarGz(filePath, targetDir string) (string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to open archive: %w", err)
+		return "", fmt.Errorf("failed to open archive: %w", err)
 	}
 	defer file.Close()
 
 	gzipReader, err := gzip.NewReader(file)
 	if err != nil {
-		return fmt.Errorf("failed to create gzip reader: %w", err)
+		return "", fmt.Errorf("failed to create gzip reader: %w", err)
 	}
 	defer gzipReader.Close()
 
@@ -351,48 +396,101 @@ func ExtractTarGz(filePath, targetDir string) error {
 		if err != nil {
 			break
 		}
+		// GitHub's source tarballs start with a pax_global_header, it holds
+		// metadata and no file
+		if header.Typeflag == tar.TypeXGlobalHeader || header.Typeflag == tar.TypeXHeader {
+			continue
+		}
 		targetPath := filepath.Join(targetDir, header.Name)
+		if !isWithin(targetPath, targetDir) {
+			return "", fmt.Errorf("archive entry %s points outside of %s", header.Name, targetDir)
+		}
+		if err = checkNoSymlinks(targetDir, targetPath); err != nil {
+			return "", err
+		}
+		if rootDir == "" {
+			rootDir, _, _ = strings.Cut(strings.TrimPrefix(header.Name, "./"), "/")
+		}
 
 		switch header.Typeflag {
 		case tar.TypeDir:
 			if err = os.MkdirAll(targetPath, 0755); err != nil {
-				return fmt.Errorf("failed to create directory: %w", err)
-			}
-			// FIX: why did I add this here, it's useless
-			if rootDir == "" {
-				rootDir = header.Name
+				return "", fmt.Errorf("failed to create directory: %w", err)
 			}
 		case tar.TypeReg:
+			// Some archives don't list parent directories explicitly
+			if err = os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+				return "", fmt.Errorf("failed to create directory: %w", err)
+			}
 			var outFile *os.File
-			outFile, err = os.Create(targetPath)
+			outFile, err = os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode)&0o777|0o600)
 			if err != nil {
-				return fmt.Errorf("failed to create target file: %w", err)
+				return "", fmt.Errorf("failed to create target file: %w", err)
 			}
 			if _, err = io.Copy(outFile, tarReader); err != nil {
 				outFile.Close() // Close on error
-				return fmt.Errorf("failed to extract file: %w", err)
+				return "", fmt.Errorf("failed to extract file: %w", err)
 			}
 			if err = outFile.Close(); err != nil {
-				return fmt.Errorf("failed to close file: %w", err)
+				return "", fmt.Errorf("failed to close file: %w", err)
 			}
 			// Check if the extracted file is the nvim binary and set executable permission
 			if strings.HasSuffix(targetPath, "bin/nvim") {
 				if err = os.Chmod(targetPath, 0755); err != nil {
-					return fmt.Errorf("failed to set executable permission: %w", err)
+					return "", fmt.Errorf("failed to set executable permission: %w", err)
 				}
 			}
+		case tar.TypeSymlink:
+			// A link leaving targetDir would let the entries after it
+			// write anywhere
+			if filepath.IsAbs(header.Linkname) || !isWithin(filepath.Join(filepath.Dir(targetPath), header.Linkname), targetDir) {
+				return "", fmt.Errorf("archive entry %s links outside of %s", header.Name, targetDir)
+			}
+			if err = os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+				return "", fmt.Errorf("failed to create directory: %w", err)
+			}
+			if err = os.Symlink(header.Linkname, targetPath); err != nil {
+				return "", fmt.Errorf("failed to create symlink: %w", err)
+			}
 		default:
-			return fmt.Errorf("unknown type: %b in %s", header.Typeflag, header.Name)
+			return "", fmt.Errorf("unknown type: %b in %s", header.Typeflag, header.Name)
 		}
 	}
 	if err != io.EOF {
-		return fmt.Errorf("error reading archive: %w", err)
+		return "", fmt.Errorf("error reading archive: %w", err)
+	}
+	return rootDir, nil
+}
+
+// checkNoSymlinks refuses paths under dir that go through a symlink, an
+// archive could otherwise write through one it created earlier
+func checkNoSymlinks(dir, path string) error {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return err
+	}
+	current := dir
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		if part == "." {
+			continue
+		}
+		current = filepath.Join(current, part)
+		fi, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("refusing to write through the symlink %s", current)
+		}
 	}
 	return nil
 }
 
 func ReadConfig() (config Config, err error) {
-	configFile, err := os.ReadFile(configPath)
+	configFile, err := os.ReadFile(ConfigPath)
 	if err != nil {
 		return config, err
 	}
@@ -400,3 +498,63 @@ func ReadConfig() (config Config, err error) {
 	err = json.Unmarshal(configFile, &config)
 	return config, err
 }
+
+// CopyDir recursively copies a directory tree, preserving file modes and symlinks
+func CopyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		targetPath := filepath.Join(dst, rel)
+
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return fmt.Errorf("failed to read symlink %s: %w", path, err)
+			}
+			return os.Symlink(link, targetPath)
+		case info.IsDir():
+			return os.MkdirAll(targetPath, info.Mode().Perm())
+		case info.Mode().IsRegular():
+			return copyFile(path, targetPath, info.Mode().Perm())
+		default:
+			// Sockets, devices and the like have no place in a neovim tree
+			return nil
+		}
+	})
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dst, err)
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	return out.Close()
+}
+
+// MoveDir renames a directory, falling back to copy and delete across filesystems
+func MoveDir(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	if err := CopyDir(src, dst); err != nil {
+		return err
+	}
+	return os.RemoveAll(src)
+}
@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// tarEntry is one entry of an archive written by writeTarGz
+type tarEntry struct {
+	header tar.Header
+	body   string
+}
+
+func writeTarGz(t *testing.T, path string, entries []tarEntry) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gzipWriter := gzip.NewWriter(file)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for _, entry := range entries {
+		entry.header.Size = int64(len(entry.body))
+		if err = tarWriter.WriteHeader(&entry.header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = tarWriter.Write([]byte(entry.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, closer := range []interface{ Close() error }{tarWriter, gzipWriter, file} {
+		if err = closer.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExtractTarGzSkipsPaxHeaders(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "neovim-0.11.0.tar.gz")
+	// Laid out like GitHub's archive/refs/tags/<tag>.tar.gz
+	writeTarGz(t, archive, []tarEntry{
+		{tar.Header{Typeflag: tar.TypeXGlobalHeader, Name: "pax_global_header", PAXRecords: map[string]string{"comment": "0123456789abcdef"}}, ""},
+		{tar.Header{Typeflag: tar.TypeDir, Name: "neovim-0.11.0/", Mode: 0o755}, ""},
+		{tar.Header{Typeflag: tar.TypeReg, Name: "neovim-0.11.0/Makefile", Mode: 0o644}, "all:\n"},
+	})
+
+	target := filepath.Join(dir, "out")
+	rootDir, err := ExtractTarGz(archive, target)
+	if err != nil {
+		t.Fatalf("ExtractTarGz: %v", err)
+	}
+	if rootDir != "neovim-0.11.0" {
+		t.Errorf("rootDir = %q, want neovim-0.11.0", rootDir)
+	}
+	if _, err = os.Stat(filepath.Join(target, "neovim-0.11.0", "Makefile")); err != nil {
+		t.Errorf("Makefile not extracted: %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(target, "pax_global_header")); !os.IsNotExist(err) {
+		t.Errorf("pax_global_header was extracted")
+	}
+}
+
+func TestExtractTarGzKeepsLinksInside(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []tarEntry
+	}{
+		{"absolute link", []tarEntry{
+			{tar.Header{Typeflag: tar.TypeDir, Name: "nvim/", Mode: 0o755}, ""},
+			{tar.Header{Typeflag: tar.TypeSymlink, Name: "nvim/x", Linkname: "OUTSIDE"}, ""},
+			{tar.Header{Typeflag: tar.TypeReg, Name: "nvim/x/.bashrc", Mode: 0o644}, "pwned\n"},
+		}},
+		{"relative link leaving the target", []tarEntry{
+			{tar.Header{Typeflag: tar.TypeDir, Name: "nvim/", Mode: 0o755}, ""},
+			{tar.Header{Typeflag: tar.TypeSymlink, Name: "nvim/x", Linkname: "../../outside"}, ""},
+			{tar.Header{Typeflag: tar.TypeReg, Name: "nvim/x/.bashrc", Mode: 0o644}, "pwned\n"},
+		}},
+		{"write through a link inside the target", []tarEntry{
+			{tar.Header{Typeflag: tar.TypeDir, Name: "nvim/share/", Mode: 0o755}, ""},
+			{tar.Header{Typeflag: tar.TypeSymlink, Name: "nvim/x", Linkname: "share"}, ""},
+			{tar.Header{Typeflag: tar.TypeReg, Name: "nvim/x/.bashrc", Mode: 0o644}, "pwned\n"},
+		}},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		outside := filepath.Join(dir, "outside")
+		if err := os.Mkdir(outside, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		for i := range tt.entries {
+			if tt.entries[i].header.Linkname == "OUTSIDE" {
+				tt.entries[i].header.Linkname = outside
+			}
+		}
+		archive := filepath.Join(dir, "evil.tar.gz")
+		writeTarGz(t, archive, tt.entries)
+
+		if _, err := ExtractTarGz(archive, filepath.Join(dir, "out")); err == nil {
+			t.Errorf("%s: ExtractTarGz accepted the archive", tt.name)
+		}
+		if _, err := os.Stat(filepath.Join(outside, ".bashrc")); !os.IsNotExist(err) {
+			t.Errorf("%s: .bashrc was written outside of the target", tt.name)
+		}
+	}
+
+	// Links between the files of a build are fine
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "nvim.tar.gz")
+	writeTarGz(t, archive, []tarEntry{
+		{tar.Header{Typeflag: tar.TypeReg, Name: "nvim/lib/libfoo.so.1", Mode: 0o644}, "elf"},
+		{tar.Header{Typeflag: tar.TypeSymlink, Name: "nvim/lib/libfoo.so", Linkname: "libfoo.so.1"}, ""},
+	})
+	if _, err := ExtractTarGz(archive, filepath.Join(dir, "out")); err != nil {
+		t.Errorf("ExtractTarGz refused an internal link: %v", err)
+	}
+}
@@ -0,0 +1,487 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+const (
+	gitHubAPIURL = "https://api.github.com"
+	neovimRepo   = "/repos/neovim/neovim"
+)
+
+// ErrNotFound is returned when the server answers 404
+var ErrNotFound = errors.New("not found")
+
+// ErrOffline is returned when something needs the network in offline mode
+var ErrOffline = errors.New("not available offline")
+
+// How long cached metadata is used without asking GitHub. The nightly
+// release and the tag list move, published releases don't.
+const (
+	movingMetadataTTL = 15 * time.Minute
+	releaseTTL        = 24 * time.Hour
+)
+
+// ReleaseInfo is the part of a release nea cares about, whatever its source
+type ReleaseInfo struct {
+	TagName   string         `json:"tag_name"`
+	NodeID    string         `json:"node_id"`
+	CreatedAt string         `json:"created_at"`
+	Assets    []ReleaseAsset `json:"assets"`
+}
+
+// RateLimitError is returned when GitHub refuses a request because the rate
+// limit is exhausted
+type RateLimitError struct {
+	Reset         time.Time
+	Authenticated bool
+}
+
+func (e *RateLimitError) Error() string {
+	msg := fmt.Sprintf("GitHub API rate limit exceeded, it resets at %s (in %s)",
+		e.Reset.Local().Format("15:04:05"), time.Until(e.Reset).Round(time.Second))
+	if !e.Authenticated {
+		msg += ". Set GITHUB_TOKEN or GH_TOKEN to raise the limit"
+	}
+	return msg
+}
+
+// CachedResponse is a response body kept along with what is needed to
+// revalidate it and to keep paginating from it
+type CachedResponse struct {
+	ETag     string
+	Link     string
+	Body     []byte
+	StoredAt time.Time
+}
+
+// ResponseCache keeps responses by URL so requests can be made conditional
+type ResponseCache interface {
+	Load(url string) (CachedResponse, bool)
+	Store(url string, response CachedResponse)
+}
+
+// memoryCache is the default ResponseCache, it lives as long as the process
+type memoryCache struct {
+	mu      sync.Mutex
+	entries map[string]CachedResponse
+}
+
+func (m *memoryCache) Load(url string) (CachedResponse, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.entries[url]
+	return entry, ok
+}
+
+func (m *memoryCache) Store(url string, response CachedResponse) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[url] = response
+}
+
+// GitHubClient talks to the GitHub REST API on behalf of every command
+type GitHubClient struct {
+	HTTPClient *http.Client
+	BaseURL    string
+	Token      string
+	Cache      ResponseCache
+	// Offline serves everything from Cache and never touches the network
+	Offline bool
+	// Notice tells that stale data is served, on stderr when nil
+	Notice func(message string)
+
+	// staleNotice tells once that stale data is served, installs run in
+	// parallel
+	staleNotice sync.Once
+}
+
+func NewGitHubClient(token string) *GitHubClient {
+	return &GitHubClient{
+		HTTPClient: httpClient,
+		BaseURL:    gitHubAPIURL,
+		Token:      token,
+		Cache:      &memoryCache{entries: make(map[string]CachedResponse)},
+	}
+}
+
+// GitHubToken returns the token from GITHUB_TOKEN, GH_TOKEN or the config
+// file, in that order. Requests are anonymous when there is none.
+func GitHubToken() string {
+	for _, name := range []string{"GITHUB_TOKEN", "GH_TOKEN"} {
+		if token := os.Getenv(name); token != "" {
+			return token
+		}
+	}
+	if config, err := ReadConfig(); err == nil {
+		return config.GitHubToken
+	}
+	return ""
+}
+
+// FetchReleases returns every tag of the neovim repository, newest first
+func (c *GitHubClient) FetchReleases() ([]Release, error) {
+	releases, err := getPaginated[Release](c, c.BaseURL+neovimRepo+"/tags?per_page=100")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch releases: %w", err)
+	}
+	return releases, nil
+}
+
+// FetchRelease returns the release with the given tag, e.g. "v0.10.4" or "nightly"
+func (c *GitHubClient) FetchRelease(tag string) (ReleaseInfo, error) {
+	var release ReleaseInfo
+	body, _, err := c.get(c.BaseURL + neovimRepo + "/releases/tags/" + tag)
+	if err != nil {
+		return release, fmt.Errorf("failed to fetch release %s: %w", tag, err)
+	}
+	if err = json.Unmarshal(body, &release); err != nil {
+		return release, fmt.Errorf("failed to parse release %s: %w", tag, err)
+	}
+	return release, nil
+}
+
+// FetchCommit returns the SHA of the commit a ref (a tag or a branch) points to
+func (c *GitHubClient) FetchCommit(ref string) (string, error) {
+	var commit struct {
+		SHA string `json:"sha"`
+	}
+	body, _, err := c.get(c.BaseURL + neovimRepo + "/commits/" + ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch commit of %s: %w", ref, err)
+	}
+	if err = json.Unmarshal(body, &commit); err != nil {
+		return "", fmt.Errorf("failed to parse commit of %s: %w", ref, err)
+	}
+	return commit.SHA, nil
+}
+
+// FetchCommitDate returns when the commit a ref points to was committed
+func (c *GitHubClient) FetchCommitDate(ref string) (time.Time, error) {
+	var commit struct {
+		Commit struct {
+			Committer struct {
+				Date time.Time `json:"date"`
+			} `json:"committer"`
+		} `json:"commit"`
+	}
+	body, _, err := c.get(c.BaseURL + neovimRepo + "/commits/" + ref)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to fetch commit %s: %w", ref, err)
+	}
+	if err = json.Unmarshal(body, &commit); err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse commit %s: %w", ref, err)
+	}
+	return commit.Commit.Committer.Date, nil
+}
+
+// ReleaseNotes is a published release with its notes
+type ReleaseNotes struct {
+	TagName     string `json:"tag_name"`
+	Name        string `json:"name"`
+	PublishedAt string `json:"published_at"`
+	URL         string `json:"html_url"`
+	Body        string `json:"body"`
+}
+
+// FetchReleaseNotes returns the notes of the release with the given tag
+func (c *GitHubClient) FetchReleaseNotes(tag string) (ReleaseNotes, error) {
+	var notes ReleaseNotes
+	body, _, err := c.get(c.BaseURL + neovimRepo + "/releases/tags/" + tag)
+	if err != nil {
+		return notes, fmt.Errorf("failed to fetch the notes of %s: %w", tag, err)
+	}
+	if err = json.Unmarshal(body, &notes); err != nil {
+		return notes, fmt.Errorf("failed to parse the notes of %s: %w", tag, err)
+	}
+	return notes, nil
+}
+
+// Commit is a commit as listed by a comparison
+type Commit struct {
+	SHA     string `json:"sha"`
+	Message string `json:"message"`
+	Author  string `json:"author"`
+	Date    string `json:"date"`
+}
+
+// Comparison is the commit range between two refs. GitHub lists at most
+// 250 commits, TotalCommits counts them all.
+type Comparison struct {
+	URL          string   `json:"html_url"`
+	TotalCommits int      `json:"total_commits"`
+	Commits      []Commit `json:"commits"`
+}
+
+// FetchComparison returns the commits reachable from head but not from base,
+// oldest first
+func (c *GitHubClient) FetchComparison(base, head string) (Comparison, error) {
+	var response struct {
+		URL          string `json:"html_url"`
+		TotalCommits int    `json:"total_commits"`
+		Commits      []struct {
+			SHA    string `json:"sha"`
+			Commit struct {
+				Message string `json:"message"`
+				Author  struct {
+					Name string `json:"name"`
+					Date string `json:"date"`
+				} `json:"author"`
+			} `json:"commit"`
+		} `json:"commits"`
+	}
+	body, _, err := c.get(c.BaseURL + neovimRepo + "/compare/" + base + "..." + head + "?per_page=250")
+	if err != nil {
+		return Comparison{}, fmt.Errorf("failed to compare %s and %s: %w", base, head, err)
+	}
+	if err = json.Unmarshal(body, &response); err != nil {
+		return Comparison{}, fmt.Errorf("failed to parse the comparison of %s and %s: %w", base, head, err)
+	}
+
+	comparison := Comparison{URL: response.URL, TotalCommits: response.TotalCommits}
+	for _, commit := range response.Commits {
+		comparison.Commits = append(comparison.Commits, Commit{
+			SHA:     commit.SHA,
+			Message: commit.Commit.Message,
+			Author:  commit.Commit.Author.Name,
+			Date:    commit.Commit.Author.Date,
+		})
+	}
+	return comparison, nil
+}
+
+// RateLimit is the state of the GitHub API rate limit
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// FetchRateLimit asks GitHub for the current rate limit. It bypasses the
+// cache, and doesn't count against the limit itself.
+func (c *GitHubClient) FetchRateLimit() (RateLimit, error) {
+	if c.Offline {
+		return RateLimit{}, fmt.Errorf("%w: cannot reach GitHub", ErrOffline)
+	}
+	req, err := http.NewRequest(http.MethodGet, c.BaseURL+"/rate_limit", nil)
+	if err != nil {
+		return RateLimit{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return RateLimit{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return RateLimit{}, fmt.Errorf("GitHub rejected the token (401 Unauthorized)")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return RateLimit{}, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var body struct {
+		Resources struct {
+			Core struct {
+				Limit     int   `json:"limit"`
+				Remaining int   `json:"remaining"`
+				Reset     int64 `json:"reset"`
+			} `json:"core"`
+		} `json:"resources"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return RateLimit{}, fmt.Errorf("failed to parse rate limit: %w", err)
+	}
+	core := body.Resources.Core
+	return RateLimit{Limit: core.Limit, Remaining: core.Remaining, Reset: time.Unix(core.Reset, 0)}, nil
+}
+
+// FetchCached GETs metadata that isn't part of the GitHub API, such as a
+// mirror's index, with the same caching and offline handling. No GitHub
+// credentials are sent.
+func (c *GitHubClient) FetchCached(url string) ([]byte, error) {
+	body, _, err := c.fetch(url, false)
+	return body, err
+}
+
+// LastModified asks a server when a file last changed, without downloading it
+func (c *GitHubClient) LastModified(url string) (time.Time, error) {
+	if c.Offline {
+		return time.Time{}, fmt.Errorf("%w: %s", ErrOffline, url)
+	}
+	resp, err := c.HTTPClient.Head(url)
+	if err != nil {
+		return time.Time{}, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return time.Time{}, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return http.ParseTime(resp.Header.Get("Last-Modified"))
+}
+
+// get performs a GitHub API request, see fetch
+func (c *GitHubClient) get(url string) ([]byte, string, error) {
+	return c.fetch(url, true)
+}
+
+// fetch performs a GET request, revalidating cached responses with their ETag.
+// It returns the body and the Link header used for pagination.
+func (c *GitHubClient) fetch(url string, api bool) ([]byte, string, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if api {
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+		if c.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.Token)
+		}
+	}
+	cached, isCached := c.Cache.Load(url)
+	if isCached && time.Since(cached.StoredAt) < metadataTTL(url) {
+		return cached.Body, cached.Link, nil
+	}
+	if c.Offline {
+		if isCached {
+			return c.serveStale(cached, "offline mode")
+		}
+		return nil, "", fmt.Errorf("%w: nothing cached for %s", ErrOffline, url)
+	}
+	if isCached && cached.ETag != "" {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		if isCached {
+			return c.serveStale(cached, "GitHub is not reachable")
+		}
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && isCached:
+		cached.StoredAt = time.Now()
+		c.Cache.Store(url, cached)
+		return cached.Body, cached.Link, nil
+	case resp.StatusCode == http.StatusOK:
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read response body: %w", err)
+		}
+		link := resp.Header.Get("Link")
+		etag := resp.Header.Get("ETag")
+		c.Cache.Store(url, CachedResponse{ETag: etag, Link: link, Body: body, StoredAt: time.Now()})
+		return body, link, nil
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, "", fmt.Errorf("%w: %s", ErrNotFound, url)
+	case api && (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests):
+		if rateErr := c.rateLimitError(resp.Header); rateErr != nil {
+			if isCached {
+				return c.serveStale(cached, "GitHub rate limit exceeded")
+			}
+			return nil, "", rateErr
+		}
+	}
+	return nil, "", fmt.Errorf("%s returned %s", url, resp.Status)
+}
+
+// CacheOnly returns a client sharing c's cache that serves everything from
+// it, however old, without a word, for output that must stay clean such as
+// shell completion. c itself is left as it is.
+func (c *GitHubClient) CacheOnly() *GitHubClient {
+	return &GitHubClient{
+		HTTPClient: c.HTTPClient,
+		BaseURL:    c.BaseURL,
+		Token:      c.Token,
+		Cache:      c.Cache,
+		Offline:    true,
+		Notice:     func(string) {},
+	}
+}
+
+// serveStale returns an expired cached response, telling the user once
+// that the data may be out of date
+func (c *GitHubClient) serveStale(cached CachedResponse, reason string) ([]byte, string, error) {
+	c.staleNotice.Do(func() {
+		message := fmt.Sprintf("%s, using stale data from %s", reason, cached.StoredAt.Local().Format("2006-01-02 15:04"))
+		if c.Notice != nil {
+			c.Notice(message)
+			return
+		}
+		fmt.Fprintln(os.Stderr, color.YellowString("Note: %s", message))
+	})
+	return cached.Body, cached.Link, nil
+}
+
+func metadataTTL(url string) time.Duration {
+	// A published stable release never changes, everything else may
+	if strings.Contains(url, "/releases/tags/v") {
+		return releaseTTL
+	}
+	return movingMetadataTTL
+}
+
+// rateLimitError builds a RateLimitError from the response headers, or
+// returns nil when the refusal isn't about rate limiting
+func (c *GitHubClient) rateLimitError(header http.Header) error {
+	// Secondary rate limits only say how long to wait
+	if retryAfter, err := strconv.Atoi(header.Get("Retry-After")); err == nil {
+		return &RateLimitError{Reset: time.Now().Add(time.Duration(retryAfter) * time.Second), Authenticated: c.Token != ""}
+	}
+	if header.Get("X-RateLimit-Remaining") != "0" {
+		return nil
+	}
+	reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &RateLimitError{Reset: time.Unix(reset, 0), Authenticated: c.Token != ""}
+}
+
+// getPaginated follows the Link header until every page has been read
+func getPaginated[T any](c *GitHubClient, url string) ([]T, error) {
+	var all []T
+	for url != "" {
+		body, link, err := c.get(url)
+		if err != nil {
+			return nil, err
+		}
+
+		var page []T
+		if err = json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON: %w", err)
+		}
+		all = append(all, page...)
+		url = nextPageURL(link)
+	}
+	return all, nil
+}
+
+var linkNextRegex = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// nextPageURL extracts the rel="next" URL of a Link header, if any
+func nextPageURL(link string) string {
+	if match := linkNextRegex.FindStringSubmatch(link); match != nil {
+		return match[1]
+	}
+	return ""
+}
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// defaultHealthCheckTimeout bounds each step of the health check when
+// config.json doesn't set one
+const defaultHealthCheckTimeout = 30 * time.Second
+
+// HealthCheckConfig holds the post-install check settings of config.json
+type HealthCheckConfig struct {
+	// Disabled skips the check, new builds are switched to as they are
+	Disabled bool `json:"disabled,omitempty"`
+	// Script is run after the built-in checks with the new binary as its
+	// argument and in $NVIM_BIN, e.g. to load a config headlessly and look
+	// for errors in :checkhealth. It fails the check by exiting non-zero.
+	Script string `json:"script,omitempty"`
+	// Timeout bounds each step, in seconds
+	Timeout int `json:"timeout,omitempty"`
+}
+
+// CheckBuild smoke-tests a freshly installed binary: it must report its
+// version, start and quit headlessly, and pass the configured script
+func CheckBuild(binary string) error {
+	config, _ := ReadConfig()
+	if config.HealthCheck.Disabled {
+		return nil
+	}
+	timeout := defaultHealthCheckTimeout
+	if config.HealthCheck.Timeout > 0 {
+		timeout = time.Duration(config.HealthCheck.Timeout) * time.Second
+	}
+
+	output, err := runCheck(timeout, binary, binary, "--version")
+	if err != nil {
+		return fmt.Errorf("'nvim --version' failed: %w", err)
+	}
+	if !strings.HasPrefix(output, "NVIM") {
+		return fmt.Errorf("'nvim --version' printed an unexpected version: %s", firstLine(output))
+	}
+	if _, err = runCheck(timeout, binary, binary, "--headless", "--clean", "+qa"); err != nil {
+		return fmt.Errorf("'nvim --headless --clean +qa' failed: %w", err)
+	}
+
+	if config.HealthCheck.Script != "" {
+		script := ExpandHome(config.HealthCheck.Script)
+		if _, err = runCheck(timeout, binary, script, binary); err != nil {
+			return fmt.Errorf("health check script %s failed: %w", script, err)
+		}
+	}
+	return nil
+}
+
+// runCheck runs one step of the health check. A failure carries the end of
+// its output, which is where crashes and errors show up.
+func runCheck(timeout time.Duration, binary, name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), "NVIM_BIN="+binary)
+	// A crashed nvim may leave children holding the output open
+	cmd.WaitDelay = time.Second
+	output, err := cmd.CombinedOutput()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return string(output), fmt.Errorf("timed out after %v", timeout)
+	}
+	if err != nil {
+		return string(output), fmt.Errorf("%w%s", err, outputTail(string(output)))
+	}
+	return string(output), nil
+}
+
+func firstLine(output string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(output), "\n")
+	return line
+}
+
+// outputTail returns the last lines of a failed step's output for its error
+func outputTail(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return ""
+	}
+	if len(lines) > 10 {
+		lines = lines[len(lines)-10:]
+	}
+	return "\n" + strings.Join(lines, "\n")
+}
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Defaults used when config.json doesn't set the network timeouts
+const (
+	defaultConnectTimeout = 30 * time.Second
+	defaultTimeout        = 10 * time.Minute
+)
+
+// NetworkConfig holds the HTTP settings of config.json. Proxies come from
+// HTTPS_PROXY, HTTP_PROXY and NO_PROXY.
+type NetworkConfig struct {
+	// CABundle is a PEM file of extra certificates to trust, e.g. a proxy's CA
+	CABundle string `json:"caBundle,omitempty"`
+	// ConnectTimeout bounds dialing and the TLS handshake, in seconds
+	ConnectTimeout int `json:"connectTimeout,omitempty"`
+	// Timeout bounds a whole request including reading the body, in seconds
+	Timeout int `json:"timeout,omitempty"`
+}
+
+// httpClient is used for every request nea makes
+var httpClient = http.DefaultClient
+
+// SetHTTPClient replaces the client every download goes through
+func SetHTTPClient(client *http.Client) {
+	httpClient = client
+}
+
+// NewHTTPClient builds the client from the network settings. Every request
+// carries a User-Agent of "nea/<version>".
+func NewHTTPClient(config NetworkConfig, version string) (*http.Client, error) {
+	connectTimeout := defaultConnectTimeout
+	if config.ConnectTimeout > 0 {
+		connectTimeout = time.Duration(config.ConnectTimeout) * time.Second
+	}
+	timeout := defaultTimeout
+	if config.Timeout > 0 {
+		timeout = time.Duration(config.Timeout) * time.Second
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	transport.DialContext = (&net.Dialer{Timeout: connectTimeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = connectTimeout
+
+	if config.CABundle != "" {
+		pool, err := loadCABundle(config.CABundle)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	return &http.Client{
+		Transport: &userAgentTransport{base: transport, userAgent: "nea/" + version},
+		Timeout:   timeout,
+	}, nil
+}
+
+// loadCABundle adds the certificates of a PEM file to the system pool
+func loadCABundle(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(ExpandHome(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in CA bundle %s", path)
+	}
+	return pool, nil
+}
+
+// ExpandHome replaces a leading ~/ with the home directory
+func ExpandHome(path string) string {
+	if rest, found := strings.CutPrefix(path, "~/"); found {
+		return filepath.Join(homeDir, rest)
+	}
+	return path
+}
+
+// userAgentTransport sets the User-Agent of requests that don't have one
+type userAgentTransport struct {
+	base      http.RoundTripper
+	userAgent string
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	return t.base.RoundTrip(req)
+}
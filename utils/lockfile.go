@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LockfileName is the file nea lock writes and install --locked reads
+const LockfileName = "nea.lock"
+
+// lockfileFormat is bumped whenever the lockfile layout changes incompatibly
+const lockfileFormat = 1
+
+// Lockfile pins every requested version to the exact build it resolved to
+type Lockfile struct {
+	Format      int           `json:"format"`
+	GeneratedAt string        `json:"generated_at"`
+	Default     string        `json:"default,omitempty"`
+	Releases    []LockedBuild `json:"releases"`
+}
+
+// LockedBuild is one version as it was when locked. Assets of every
+// platform are recorded so the lockfile works for the whole team.
+type LockedBuild struct {
+	// Version is "nightly" or a stable version like "0.10.4"
+	Version   string        `json:"version"`
+	Tag       string        `json:"tag"`
+	NodeID    string        `json:"node_id"`
+	CreatedAt string        `json:"created_at"`
+	Commit    string        `json:"commit,omitempty"`
+	Assets    []LockedAsset `json:"assets"`
+}
+
+type LockedAsset struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+}
+
+// Release returns the locked build as the release it came from
+func (b LockedBuild) Release() ReleaseInfo {
+	release := ReleaseInfo{TagName: b.Tag, NodeID: b.NodeID, CreatedAt: b.CreatedAt}
+	for _, asset := range b.Assets {
+		release.Assets = append(release.Assets, ReleaseAsset{
+			Name:               asset.Name,
+			BrowserDownloadURL: asset.URL,
+			Digest:             "sha256:" + asset.SHA256,
+		})
+	}
+	return release
+}
+
+// FindLockfile looks for nea.lock in dir and its parents
+func FindLockfile(dir string) (string, error) {
+	return findUpwards(dir, LockfileName)
+}
+
+// ReadLockfile parses a nea.lock
+func ReadLockfile(path string) (Lockfile, error) {
+	var lockfile Lockfile
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return lockfile, fmt.Errorf("failed to read lockfile: %w", err)
+	}
+	if err = json.Unmarshal(data, &lockfile); err != nil {
+		return lockfile, fmt.Errorf("failed to parse lockfile %s: %w", path, err)
+	}
+	if lockfile.Format != lockfileFormat {
+		return lockfile, fmt.Errorf("unsupported lockfile format %d, expected %d", lockfile.Format, lockfileFormat)
+	}
+	return lockfile, nil
+}
+
+// WriteLockfile writes a nea.lock, builds keep the order they were given in
+func WriteLockfile(path string, lockfile Lockfile) error {
+	lockfile.Format = lockfileFormat
+	data, err := json.MarshalIndent(lockfile, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize lockfile: %w", err)
+	}
+	if err = os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write lockfile: %w", err)
+	}
+	return nil
+}
+
+// findUpwards looks for a file named name in dir and its parents
+func findUpwards(dir, name string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("no %s found in this directory or its parents", name)
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,183 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ManifestName is the file nea sync looks for
+const ManifestName = "nea.toml"
+
+// Manifest declares the versions a project or a team expects, e.g.
+//
+//	default = "0.10.4"
+//	stable = ["0.9.5", "0.10.4"]
+//	nightly = true
+//	nightly_pins = ["2025-01-15"]
+type Manifest struct {
+	Path string
+	// Default is the version to use once synced, anything 'nea use' accepts
+	Default string
+	// Stable lists the stable versions, "stable" meaning the latest one
+	Stable []string
+	// Nightly tracks the latest nightly
+	Nightly bool
+	// NightlyPins are nightly dates (YYYY-MM-DD) to keep installed
+	NightlyPins []string
+}
+
+var nightlyDateRegex = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+
+// FindManifest looks for nea.toml in dir and its parents
+func FindManifest(dir string) (string, error) {
+	return findUpwards(dir, ManifestName)
+}
+
+// ReadManifest parses a nea.toml
+func ReadManifest(path string) (Manifest, error) {
+	manifest := Manifest{Path: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return manifest, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	values, err := parseTOML(string(data))
+	if err != nil {
+		return manifest, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for key, value := range values {
+		var ok bool
+		switch key {
+		case "default":
+			manifest.Default, ok = value.(string)
+		case "stable":
+			manifest.Stable, ok = value.([]string)
+		case "nightly":
+			manifest.Nightly, ok = value.(bool)
+		case "nightly_pins":
+			manifest.NightlyPins, ok = value.([]string)
+			for _, pin := range manifest.NightlyPins {
+				if !nightlyDateRegex.MatchString(pin) {
+					return manifest, fmt.Errorf("%s: nightly pin '%s' is not a date like 2025-01-15", path, pin)
+				}
+			}
+		default:
+			return manifest, fmt.Errorf("%s: unknown key '%s'", path, key)
+		}
+		if !ok {
+			return manifest, fmt.Errorf("%s: '%s' has the wrong type", path, key)
+		}
+	}
+	return manifest, nil
+}
+
+// parseTOML reads the small part of TOML a manifest needs: comments,
+// [tables], and keys holding a string, a boolean, an integer or an array of
+// strings. Keys inside a table are returned as "table.key".
+func parseTOML(content string) (map[string]any, error) {
+	values := make(map[string]any)
+	table := ""
+
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNumber := i + 1
+		line := strings.TrimSpace(stripComment(lines[i]))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: invalid table header", lineNumber)
+			}
+			table = strings.TrimSpace(line[1:len(line)-1]) + "."
+			continue
+		}
+
+		key, raw, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNumber)
+		}
+		key = table + strings.Trim(strings.TrimSpace(key), `"`)
+		raw = strings.TrimSpace(raw)
+
+		// Arrays may span several lines
+		if strings.HasPrefix(raw, "[") {
+			for !strings.HasSuffix(raw, "]") && i+1 < len(lines) {
+				i++
+				raw += " " + strings.TrimSpace(stripComment(lines[i]))
+			}
+		}
+
+		value, err := parseTOMLValue(raw)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		if _, exists := values[key]; exists {
+			return nil, fmt.Errorf("line %d: '%s' is defined twice", lineNumber, key)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+func parseTOMLValue(raw string) (any, error) {
+	switch {
+	case raw == "true" || raw == "false":
+		return raw == "true", nil
+	case strings.HasPrefix(raw, `"`) || strings.HasPrefix(raw, "'"):
+		return parseTOMLString(raw)
+	case strings.HasPrefix(raw, "["):
+		if !strings.HasSuffix(raw, "]") {
+			return nil, fmt.Errorf("unterminated array")
+		}
+		items := []string{}
+		for _, item := range strings.Split(raw[1:len(raw)-1], ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			str, err := parseTOMLString(item)
+			if err != nil {
+				return nil, fmt.Errorf("arrays may only hold strings: %w", err)
+			}
+			items = append(items, str)
+		}
+		return items, nil
+	default:
+		number, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported value %s", raw)
+		}
+		return number, nil
+	}
+}
+
+func parseTOMLString(raw string) (string, error) {
+	if len(raw) >= 2 && raw[0] == '\'' && raw[len(raw)-1] == '\'' {
+		return raw[1 : len(raw)-1], nil
+	}
+	str, err := strconv.Unquote(raw)
+	if err != nil || !strings.HasPrefix(raw, `"`) {
+		return "", fmt.Errorf("invalid string %s", raw)
+	}
+	return str, nil
+}
+
+// stripComment drops a # comment, leaving # inside strings alone
+func stripComment(line string) string {
+	var quote rune
+	for i, char := range line {
+		switch {
+		case quote != 0 && char == quote:
+			quote = 0
+		case quote == 0 && (char == '"' || char == '\''):
+			quote = char
+		case quote == 0 && char == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
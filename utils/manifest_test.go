@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	content := `# nea.toml
+default = "0.10.4"   # trailing comment
+stable = ["0.9.5", '0.10.4',]
+nightly = true
+jobs = 4
+url = "http://example.com/#anchor"
+quoted = "say \"hi\" # not a comment"
+pins = [
+  "2025-01-15", # first
+  "2025-02-01",
+]
+
+[source]
+"type" = "mirror"
+`
+	values, err := parseTOML(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"default":     "0.10.4",
+		"stable":      []string{"0.9.5", "0.10.4"},
+		"nightly":     true,
+		"jobs":        4,
+		"url":         "http://example.com/#anchor",
+		"quoted":      `say "hi" # not a comment`,
+		"pins":        []string{"2025-01-15", "2025-02-01"},
+		"source.type": "mirror",
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("parseTOML =\n%#v\nwant\n%#v", values, want)
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	tests := map[string]string{
+		"[table":                "line 1: invalid table header",
+		"key":                   "line 1: expected key = value",
+		"a = 1\na = 2":          "line 2: 'a' is defined twice",
+		`a = "open`:             "line 1: invalid string",
+		"a = [1, 2]":            "arrays may only hold strings",
+		"a = [\"x\"":            "unterminated array",
+		"a = 1.5":               "unsupported value 1.5",
+		"\n\nb = yes # comment": "line 3: unsupported value yes",
+	}
+	for content, want := range tests {
+		_, err := parseTOML(content)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("parseTOML(%q) = %v, want an error containing %q", content, err, want)
+		}
+	}
+}
+
+func TestReadManifest(t *testing.T) {
+	tests := []struct {
+		content string
+		want    Manifest
+		err     string
+	}{
+		{
+			content: "default = \"nightly\"\nstable = [\"stable\", \"0.9.5\"]\nnightly = true\nnightly_pins = [\"2025-01-15\"]\n",
+			want:    Manifest{Default: "nightly", Stable: []string{"stable", "0.9.5"}, Nightly: true, NightlyPins: []string{"2025-01-15"}},
+		},
+		{content: "stable = []\n", want: Manifest{Stable: []string{}}},
+		{content: "nightly = \"yes\"\n", err: "'nightly' has the wrong type"},
+		{content: "nightly_pins = [\"last week\"]\n", err: "nightly pin 'last week' is not a date"},
+		{content: "versions = [\"0.9.5\"]\n", err: "unknown key 'versions'"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), ManifestName)
+		if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		manifest, err := ReadManifest(path)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ReadManifest(%q) = %v, want an error containing %q", tt.content, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ReadManifest(%q): %v", tt.content, err)
+			continue
+		}
+		tt.want.Path = path
+		if !reflect.DeepEqual(manifest, tt.want) {
+			t.Errorf("ReadManifest(%q) = %+v, want %+v", tt.content, manifest, tt.want)
+		}
+	}
+}
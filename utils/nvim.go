@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// NvimVersion runs `nvim --version` and returns the version from its first
+// line, e.g. "v0.10.2" or "v0.11.0-dev-1234+gabcdef0".
+func NvimVersion(binary string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, binary, "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to run %s --version: %w", binary, err)
+	}
+
+	firstLine, _, _ := strings.Cut(string(output), "\n")
+	version, found := strings.CutPrefix(strings.TrimSpace(firstLine), "NVIM ")
+	if !found {
+		return "", fmt.Errorf("%s does not look like a Neovim binary", binary)
+	}
+	return version, nil
+}
+
+// BuildInfo is the provenance of a build, as reported by 'nvim --version'
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildType string `json:"build_type,omitempty"`
+	LuaJIT    string `json:"luajit,omitempty"`
+	// Flags are the compilation flags, only printed by older versions
+	Flags string `json:"flags,omitempty"`
+}
+
+// ReadBuildInfo runs `nvim --version` and parses every line of it
+func ReadBuildInfo(binary string) (BuildInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, binary, "--version").Output()
+	if err != nil {
+		return BuildInfo{}, fmt.Errorf("failed to run %s --version: %w", binary, err)
+	}
+	return ParseBuildInfo(string(output))
+}
+
+// ParseBuildInfo parses the output of `nvim --version`:
+//
+//	NVIM v0.11.0-dev-1234+gabcdef0
+//	Build type: RelWithDebInfo
+//	LuaJIT 2.1.1713484068
+//	Compilation: /usr/bin/cc -O2 ...
+func ParseBuildInfo(output string) (BuildInfo, error) {
+	var info BuildInfo
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if version, found := strings.CutPrefix(line, "NVIM "); found && info.Version == "" {
+			info.Version = version
+			info.Commit = CommitFromVersion(version)
+		} else if buildType, found := strings.CutPrefix(line, "Build type:"); found {
+			info.BuildType = strings.TrimSpace(buildType)
+		} else if luaJIT, found := strings.CutPrefix(line, "LuaJIT "); found {
+			info.LuaJIT = luaJIT
+		} else if flags, found := strings.CutPrefix(line, "Compilation:"); found {
+			info.Flags = strings.TrimSpace(flags)
+		}
+	}
+	if info.Version == "" {
+		return info, fmt.Errorf("not a Neovim version output")
+	}
+	return info, nil
+}
+
+// CommitFromVersion returns the abbreviated commit a development build's
+// version names, e.g. "abcdef0" for "v0.11.0-dev-1234+gabcdef0", or "" for
+// a release
+func CommitFromVersion(version string) string {
+	_, commit, found := strings.Cut(version, "+g")
+	if !found {
+		return ""
+	}
+	commit, _, _ = strings.Cut(commit, "-")
+	return commit
+}
+
+// FindNvimBinary accepts either a path to an nvim binary or an installation
+// prefix containing bin/nvim, and returns the binary and its prefix.
+func FindNvimBinary(path string) (binary string, prefix string, err error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+
+	fi, err := os.Stat(absPath)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to access %s: %w", absPath, err)
+	}
+
+	if fi.IsDir() {
+		binary = filepath.Join(absPath, "bin", "nvim")
+		if _, err = os.Stat(binary); err != nil {
+			return "", "", fmt.Errorf("no bin/nvim found under %s", absPath)
+		}
+		return binary, absPath, nil
+	}
+
+	if fi.Mode()&0o111 == 0 {
+		return "", "", fmt.Errorf("%s is not executable", absPath)
+	}
+	prefix = filepath.Dir(absPath)
+	if filepath.Base(prefix) == "bin" {
+		prefix = filepath.Dir(prefix)
+	}
+	return absPath, prefix, nil
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseBuildInfo(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   BuildInfo
+	}{
+		{
+			name: "nightly",
+			output: `NVIM v0.11.0-dev-1234+gabcdef0
+Build type: RelWithDebInfo
+LuaJIT 2.1.1713484068
+Run "nvim -V1 -v" for more info
+`,
+			want: BuildInfo{Version: "v0.11.0-dev-1234+gabcdef0", Commit: "abcdef0", BuildType: "RelWithDebInfo", LuaJIT: "2.1.1713484068"},
+		},
+		{
+			name:   "release",
+			output: "NVIM v0.10.4\r\nBuild type: Release\r\nLuaJIT 2.1.1713484068\r\n",
+			want:   BuildInfo{Version: "v0.10.4", BuildType: "Release", LuaJIT: "2.1.1713484068"},
+		},
+		{
+			name: "older build with compilation flags",
+			output: `NVIM v0.9.5
+Build type: Release
+LuaJIT 2.1.1692716794
+Compilation: /usr/bin/cc -O2 -g -Wall
+`,
+			want: BuildInfo{Version: "v0.9.5", BuildType: "Release", LuaJIT: "2.1.1692716794", Flags: "/usr/bin/cc -O2 -g -Wall"},
+		},
+		{
+			name:   "dirty development build",
+			output: "NVIM v0.12.0-dev-42+g0123abc-dirty\n",
+			want:   BuildInfo{Version: "v0.12.0-dev-42+g0123abc-dirty", Commit: "0123abc"},
+		},
+	}
+	for _, tt := range tests {
+		got, err := ParseBuildInfo(tt.output)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ParseBuildInfo = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := ParseBuildInfo("vim 9.1\n"); err == nil {
+		t.Errorf("ParseBuildInfo accepted the output of vim")
+	}
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// ErrNoCompatibleBuild is returned when a release has no prebuilt binary
+// that can run on this system, in which case building from source is the way out
+var ErrNoCompatibleBuild = errors.New("no compatible prebuilt binary")
+
+// Platform describes what the assets have to run on
+type Platform struct {
+	OS           string
+	Arch         string
+	Libc         string // glibc or musl, empty on macOS
+	GlibcVersion string // e.g. 2.31, empty when unknown
+}
+
+// CurrentPlatform detects the OS, architecture and, on Linux, the libc flavor
+func CurrentPlatform() Platform {
+	platform := Platform{OS: runtime.GOOS, Arch: runtime.GOARCH}
+	if platform.OS == "linux" {
+		platform.Libc, platform.GlibcVersion = detectLibc()
+	}
+	return platform
+}
+
+func detectLibc() (string, string) {
+	// glibc reports itself as "glibc 2.31"
+	if output, err := exec.Command("getconf", "GNU_LIBC_VERSION").Output(); err == nil {
+		if version, found := strings.CutPrefix(strings.TrimSpace(string(output)), "glibc "); found {
+			return "glibc", version
+		}
+	}
+
+	// musl ships its dynamic loader as /lib/ld-musl-<arch>.so.1
+	if matches, _ := filepath.Glob("/lib/ld-musl-*.so.1"); len(matches) > 0 {
+		return "musl", ""
+	}
+	// musl's ldd prints its banner on stderr and exits with an error
+	output, _ := exec.Command("ldd", "--version").CombinedOutput()
+	if strings.Contains(strings.ToLower(string(output)), "musl") {
+		return "musl", ""
+	}
+	return "glibc", ""
+}
+
+// minimumGlibc returns the oldest glibc the official Linux builds of a
+// version run on, as stated in the release notes
+func minimumGlibc(version string) string {
+	if version == "nightly" || semver.Compare("v"+version, "v0.10.0") >= 0 {
+		return "2.31"
+	}
+	return "2.17"
+}
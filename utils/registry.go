@@ -0,0 +1,244 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Kinds of entries stored in versions_info.json. Entries written before
+// kinds existed have an empty kind and are treated as nightlies.
+const (
+	KindNightly = "nightly"
+	KindStable  = "stable"
+	KindCustom  = "custom"
+)
+
+// IsNightly reports whether the entry is a nightly build managed by nea.
+func (v VersionInfo) IsNightly() bool {
+	return v.Kind == "" || v.Kind == KindNightly
+}
+
+// ReadRegistry returns every entry in versions_info.json, whatever its kind.
+func ReadRegistry() ([]VersionInfo, error) {
+	data, err := os.ReadFile(versionFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read versions info file: %w", err)
+	}
+
+	var entries []VersionInfo
+	if err = json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse versions info JSON: %w", err)
+	}
+	return entries, nil
+}
+
+// WriteRegistry replaces the whole content of versions_info.json.
+func WriteRegistry(entries []VersionInfo) error {
+	if entries == nil {
+		entries = []VersionInfo{}
+	}
+	updatedJson, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal versions info: %w", err)
+	}
+	if err = os.WriteFile(versionFilePath, updatedJson, 0o644); err != nil {
+		return fmt.Errorf("failed to write versions info: %w", err)
+	}
+	return nil
+}
+
+// WriteVersionsInfo replaces the nightly entries of the registry while
+// keeping every other kind untouched.
+func WriteVersionsInfo(nightlies []VersionInfo) error {
+	entries, err := ReadRegistry()
+	if err != nil {
+		return err
+	}
+
+	updated := make([]VersionInfo, 0, len(entries)+len(nightlies))
+	for _, entry := range entries {
+		if !entry.IsNightly() {
+			updated = append(updated, entry)
+		}
+	}
+	updated = append(updated, nightlies...)
+	return WriteRegistry(updated)
+}
+
+// AddVersionInfo appends an entry to the registry. Nightlies are renumbered
+// so their UniqueNumber keeps matching the rollback step.
+func AddVersionInfo(entry VersionInfo) error {
+	if !entry.IsNightly() {
+		entries, err := ReadRegistry()
+		if err != nil {
+			return err
+		}
+		return WriteRegistry(append(entries, entry))
+	}
+
+	versions, err := ReadVersionsInfo()
+	if err != nil {
+		return err
+	}
+	versions = append(versions, entry)
+	SortVersionsDesc(versions)
+	for i := range versions {
+		versions[i].UniqueNumber = i
+	}
+	return WriteVersionsInfo(versions)
+}
+
+// RemoveVersionInfo drops every registry entry matching the predicate.
+func RemoveVersionInfo(match func(VersionInfo) bool) error {
+	entries, err := ReadRegistry()
+	if err != nil {
+		return err
+	}
+
+	kept := make([]VersionInfo, 0, len(entries))
+	for _, entry := range entries {
+		if !match(entry) {
+			kept = append(kept, entry)
+		}
+	}
+	return WriteRegistry(kept)
+}
+
+// FindStableVersion looks up the registry entry of a stable version, if any.
+// Stables installed before they were registered only exist on disk.
+func FindStableVersion(version string) (VersionInfo, bool) {
+	entries, err := ReadRegistry()
+	if err != nil {
+		return VersionInfo{}, false
+	}
+	for _, entry := range entries {
+		if entry.Kind == KindStable && entry.Version == version {
+			return entry, true
+		}
+	}
+	return VersionInfo{}, false
+}
+
+// FindNightlyByCommit looks up an installed nightly by the commit it was
+// built from, or a prefix of it at least 7 characters long. It returns its
+// rollback step.
+func FindNightlyByCommit(commit string) (int, VersionInfo, bool) {
+	if len(commit) < 7 || strings.Trim(strings.ToLower(commit), "0123456789abcdef") != "" {
+		return -1, VersionInfo{}, false
+	}
+	versions, err := ReadVersionsInfo()
+	if err != nil {
+		return -1, VersionInfo{}, false
+	}
+	for step, version := range versions {
+		if version.Build == nil || version.Build.Commit == "" {
+			continue
+		}
+		// Versions abbreviate the commit, so either may be the prefix
+		known, wanted := strings.ToLower(version.Build.Commit), strings.ToLower(commit)
+		if strings.HasPrefix(known, wanted) || strings.HasPrefix(wanted, known) {
+			return step, version, true
+		}
+	}
+	return -1, VersionInfo{}, false
+}
+
+// RegisterStableVersion records an installed stable, replacing any stale
+// entry left for the same version.
+func RegisterStableVersion(entry VersionInfo) error {
+	entry.Kind = KindStable
+	err := RemoveVersionInfo(func(v VersionInfo) bool {
+		return v.Kind == KindStable && v.Version == entry.Version
+	})
+	if err != nil {
+		return err
+	}
+	return AddVersionInfo(entry)
+}
+
+// ReadLinkedVersions returns the externally-built binaries registered with `link`.
+func ReadLinkedVersions() ([]VersionInfo, error) {
+	entries, err := ReadRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	linked := make([]VersionInfo, 0)
+	for _, entry := range entries {
+		if entry.Kind == KindCustom {
+			linked = append(linked, entry)
+		}
+	}
+	return linked, nil
+}
+
+// FindLinkedVersion looks up a linked version by its name.
+func FindLinkedVersion(name string) (VersionInfo, bool) {
+	linked, err := ReadLinkedVersions()
+	if err != nil {
+		return VersionInfo{}, false
+	}
+	for _, entry := range linked {
+		if entry.Name == name {
+			return entry, true
+		}
+	}
+	return VersionInfo{}, false
+}
+
+// RegisterLinkedVersion adds an external binary under the given name.
+func RegisterLinkedVersion(name, directory, binary, version string) error {
+	if _, exists := FindLinkedVersion(name); exists {
+		return fmt.Errorf("a linked version named '%s' already exists", name)
+	}
+
+	entries, err := ReadRegistry()
+	if err != nil {
+		return err
+	}
+	entries = append(entries, VersionInfo{
+		Kind:      KindCustom,
+		Name:      name,
+		Version:   version,
+		Binary:    binary,
+		Directory: directory,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	})
+	return WriteRegistry(entries)
+}
+
+// UnregisterLinkedVersion removes a linked version from the registry. The
+// files it points to are never touched since nea doesn't own them.
+func UnregisterLinkedVersion(name string) error {
+	entries, err := ReadRegistry()
+	if err != nil {
+		return err
+	}
+
+	for i, entry := range entries {
+		if entry.Kind == KindCustom && entry.Name == name {
+			entries = append(entries[:i], entries[i+1:]...)
+			return WriteRegistry(entries)
+		}
+	}
+	return fmt.Errorf("linked version %s not found", name)
+}
+
+// ValidateLinkName rejects names that would be mistaken for a managed version.
+func ValidateLinkName(name string) error {
+	lower := strings.ToLower(name)
+	switch {
+	case name == "":
+		return fmt.Errorf("name must not be empty")
+	case lower == "stable" || lower == "nightly" || lower == "all":
+		return fmt.Errorf("'%s' is a reserved name", name)
+	case strings.HasPrefix(name, "0.") || strings.HasPrefix(name, "v0.") || strings.HasPrefix(name, "20"):
+		return fmt.Errorf("'%s' looks like a version or nightly date, please pick another name", name)
+	case strings.ContainsAny(name, `/\ `):
+		return fmt.Errorf("name must not contain slashes or spaces")
+	}
+	return nil
+}
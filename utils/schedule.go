@@ -0,0 +1,327 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Schedulers nea can register the update job with
+const (
+	SchedulerSystemd = "systemd"
+	SchedulerCron    = "cron"
+	SchedulerLaunchd = "launchd"
+)
+
+const (
+	scheduleUnitName   = "nea-update"
+	scheduleCronMarker = "# nea-update"
+	scheduleLaunchdID  = "com.nea.update"
+)
+
+// LogDir is where background jobs write their output
+var LogDir = filepath.Join(appDir, "logs")
+
+// UpdateLogPath is the log of the scheduled nightly update
+var UpdateLogPath = filepath.Join(LogDir, "update.log")
+
+// ScheduleStatus describes the scheduled update found on this machine
+type ScheduleStatus struct {
+	Scheduler string
+	Enabled   bool
+	// Details is what the scheduler reports, e.g. the next run
+	Details string
+}
+
+var (
+	systemdUserDir  = filepath.Join(homeDir, ".config", "systemd", "user")
+	launchAgentPath = filepath.Join(homeDir, "Library", "LaunchAgents", scheduleLaunchdID+".plist")
+)
+
+// DetectScheduler picks the scheduler to use: launchd on macOS, a systemd
+// user instance when one runs, cron otherwise
+func DetectScheduler() (string, error) {
+	if runtime.GOOS == "darwin" {
+		return SchedulerLaunchd, nil
+	}
+	if _, err := exec.LookPath("systemctl"); err == nil {
+		if exec.Command("systemctl", "--user", "show-environment").Run() == nil {
+			return SchedulerSystemd, nil
+		}
+	}
+	if _, err := exec.LookPath("crontab"); err == nil {
+		return SchedulerCron, nil
+	}
+	return "", fmt.Errorf("no supported scheduler found (systemd user instance, cron or launchd)")
+}
+
+// EnableSchedule registers command to run every day at hour:minute with the
+// given scheduler, replacing a previous registration
+func EnableSchedule(scheduler string, hour, minute int, command []string) error {
+	if err := os.MkdirAll(LogDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+	switch scheduler {
+	case SchedulerSystemd:
+		return enableSystemd(hour, minute, command)
+	case SchedulerCron:
+		return enableCron(hour, minute, command)
+	case SchedulerLaunchd:
+		return enableLaunchd(hour, minute, command)
+	}
+	return fmt.Errorf("unknown scheduler '%s'", scheduler)
+}
+
+// DisableSchedule removes the update job from every scheduler it is found in
+func DisableSchedule() ([]string, error) {
+	var removed []string
+	for _, status := range ScheduleStatuses() {
+		if !status.Enabled {
+			continue
+		}
+		var err error
+		switch status.Scheduler {
+		case SchedulerSystemd:
+			err = disableSystemd()
+		case SchedulerCron:
+			err = disableCron()
+		case SchedulerLaunchd:
+			err = disableLaunchd()
+		}
+		if err != nil {
+			return removed, err
+		}
+		removed = append(removed, status.Scheduler)
+	}
+	return removed, nil
+}
+
+// ScheduleStatuses reports the update job of every scheduler present here
+func ScheduleStatuses() []ScheduleStatus {
+	var statuses []ScheduleStatus
+	if _, err := os.Stat(filepath.Join(systemdUserDir, scheduleUnitName+".timer")); err == nil {
+		output, _ := exec.Command("systemctl", "--user", "list-timers", "--all", scheduleUnitName+".timer").Output()
+		statuses = append(statuses, ScheduleStatus{Scheduler: SchedulerSystemd, Enabled: true, Details: strings.TrimSpace(string(output))})
+	}
+	if line := cronLine(); line != "" {
+		statuses = append(statuses, ScheduleStatus{Scheduler: SchedulerCron, Enabled: true, Details: line})
+	}
+	if _, err := os.Stat(launchAgentPath); err == nil {
+		statuses = append(statuses, ScheduleStatus{Scheduler: SchedulerLaunchd, Enabled: true, Details: launchAgentPath})
+	}
+	return statuses
+}
+
+func enableSystemd(hour, minute int, command []string) error {
+	if err := os.MkdirAll(systemdUserDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", systemdUserDir, err)
+	}
+
+	service := fmt.Sprintf(`[Unit]
+Description=Update the Neovim nightly managed by nea
+
+[Service]
+Type=oneshot
+ExecStart=%s
+StandardOutput=append:%s
+StandardError=append:%s
+`, systemdCommand(command), systemdEscape(UpdateLogPath), systemdEscape(UpdateLogPath))
+	timer := fmt.Sprintf(`[Unit]
+Description=Update the Neovim nightly managed by nea every day
+
+[Timer]
+OnCalendar=*-*-* %02d:%02d:00
+Persistent=true
+
+[Install]
+WantedBy=timers.target
+`, hour, minute)
+
+	files := map[string]string{scheduleUnitName + ".service": service, scheduleUnitName + ".timer": timer}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(systemdUserDir, name), []byte(content), 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+
+	if err := runScheduler("systemctl", "--user", "daemon-reload"); err != nil {
+		return err
+	}
+	return runScheduler("systemctl", "--user", "enable", "--now", scheduleUnitName+".timer")
+}
+
+func disableSystemd() error {
+	// The timer may already be stopped, the unit files are what matter
+	runScheduler("systemctl", "--user", "disable", "--now", scheduleUnitName+".timer")
+	for _, name := range []string{scheduleUnitName + ".timer", scheduleUnitName + ".service"} {
+		if err := os.Remove(filepath.Join(systemdUserDir, name)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", name, err)
+		}
+	}
+	return runScheduler("systemctl", "--user", "daemon-reload")
+}
+
+// systemdCommand quotes the arguments of ExecStart that need it, such as
+// paths with spaces
+func systemdCommand(command []string) string {
+	quoted := make([]string, len(command))
+	for i, arg := range command {
+		arg = strings.ReplaceAll(systemdEscape(arg), "$", "$$")
+		if arg != "" && !strings.ContainsAny(arg, " \t'\"\\;") {
+			quoted[i] = arg
+			continue
+		}
+		quoted[i] = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+	}
+	return strings.Join(quoted, " ")
+}
+
+// systemdEscape keeps systemd from expanding specifiers such as %h in a
+// value of a unit file
+func systemdEscape(value string) string {
+	return strings.ReplaceAll(value, "%", "%%")
+}
+
+func enableCron(hour, minute int, command []string) error {
+	return writeCrontab(append(cronLinesWithout(), cronJob(hour, minute, command)))
+}
+
+// cronJob is the crontab line of the update job
+func cronJob(hour, minute int, command []string) string {
+	job := fmt.Sprintf("%s >> %s 2>&1", shellCommand(command), ShellQuote(UpdateLogPath))
+	// cron ends the command at an unescaped %, even inside quotes
+	job = strings.ReplaceAll(job, "%", `\%`)
+	return fmt.Sprintf("%d %d * * * %s %s", minute, hour, job, scheduleCronMarker)
+}
+
+func disableCron() error {
+	return writeCrontab(cronLinesWithout())
+}
+
+// cronLine returns the update job's crontab line, if any
+func cronLine() string {
+	if _, err := exec.LookPath("crontab"); err != nil {
+		return ""
+	}
+	output, _ := exec.Command("crontab", "-l").Output()
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.HasSuffix(line, scheduleCronMarker) {
+			return line
+		}
+	}
+	return ""
+}
+
+// cronLinesWithout returns the current crontab without the update job.
+// crontab -l fails when there is no crontab yet, which is an empty one.
+func cronLinesWithout() []string {
+	output, _ := exec.Command("crontab", "-l").Output()
+	var lines []string
+	for _, line := range strings.Split(strings.TrimRight(string(output), "\n"), "\n") {
+		if line != "" && !strings.HasSuffix(line, scheduleCronMarker) {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func writeCrontab(lines []string) error {
+	cmd := exec.Command("crontab", "-")
+	cmd.Stdin = strings.NewReader(strings.Join(lines, "\n") + "\n")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to update crontab: %w\n%s", err, output)
+	}
+	return nil
+}
+
+func enableLaunchd(hour, minute int, command []string) error {
+	var arguments bytes.Buffer
+	for _, arg := range command {
+		arguments.WriteString("\t\t<string>" + xmlEscape(arg) + "</string>\n")
+	}
+	plist := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+%s	</array>
+	<key>StartCalendarInterval</key>
+	<dict>
+		<key>Hour</key>
+		<integer>%d</integer>
+		<key>Minute</key>
+		<integer>%d</integer>
+	</dict>
+	<key>StandardOutPath</key>
+	<string>%s</string>
+	<key>StandardErrorPath</key>
+	<string>%s</string>
+</dict>
+</plist>
+`, scheduleLaunchdID, arguments.String(), hour, minute, xmlEscape(UpdateLogPath), xmlEscape(UpdateLogPath))
+
+	if err := os.MkdirAll(filepath.Dir(launchAgentPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(launchAgentPath), err)
+	}
+	// Reloading is the only way to make launchd pick up changes
+	exec.Command("launchctl", "unload", launchAgentPath).Run()
+	if err := os.WriteFile(launchAgentPath, []byte(plist), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", launchAgentPath, err)
+	}
+	return runScheduler("launchctl", "load", "-w", launchAgentPath)
+}
+
+func disableLaunchd() error {
+	exec.Command("launchctl", "unload", "-w", launchAgentPath).Run()
+	if err := os.Remove(launchAgentPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", launchAgentPath, err)
+	}
+	return nil
+}
+
+func runScheduler(name string, args ...string) error {
+	if output, err := exec.Command(name, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s %s failed: %w\n%s", name, strings.Join(args, " "), err, output)
+	}
+	return nil
+}
+
+func shellCommand(command []string) string {
+	quoted := make([]string, len(command))
+	for i, arg := range command {
+		quoted[i] = ShellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// ShellQuote quotes a value for sh, words that need no quoting are kept as is
+func ShellQuote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t'\"\\$`!*?;&|<>()[]{}#~%") {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+func xmlEscape(value string) string {
+	var escaped bytes.Buffer
+	for _, char := range value {
+		switch char {
+		case '&':
+			escaped.WriteString("&amp;")
+		case '<':
+			escaped.WriteString("&lt;")
+		case '>':
+			escaped.WriteString("&gt;")
+		default:
+			escaped.WriteRune(char)
+		}
+	}
+	return escaped.String()
+}
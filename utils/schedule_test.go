@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSystemdCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		command []string
+		want    string
+	}{
+		{"plain", []string{"/usr/bin/nea", "update", "--quiet"}, "/usr/bin/nea update --quiet"},
+		{"spaces", []string{"/home/me/My Tools/nea", "update"}, `"/home/me/My Tools/nea" update`},
+		{"specifiers", []string{"/opt/100%/nea", "$HOME"}, "/opt/100%%/nea $$HOME"},
+		{"quotes", []string{`/opt/a "b"\c/nea`}, `"/opt/a \"b\"\\c/nea"`},
+	}
+	for _, tt := range tests {
+		if got := systemdCommand(tt.command); got != tt.want {
+			t.Errorf("%s: systemdCommand = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCronJobEscapesPercent(t *testing.T) {
+	line := cronJob(3, 30, []string{"/opt/100%/nea", "update"})
+	if !strings.HasPrefix(line, `30 3 * * * '/opt/100\%/nea' update >> `) {
+		t.Errorf("cronJob = %s", line)
+	}
+	if strings.Contains(strings.ReplaceAll(line, `\%`, ""), "%") {
+		t.Errorf("cronJob left a bare %% in %s", line)
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"os"
 	"path/filepath"
 	"slices"
-	"strings"
 
 	"github.com/fatih/color"
 )
@@ -47,10 +46,18 @@ func Setup() error {
 
 	// Check and notify about PATH setup
 	binDir := filepath.Join(appDir, "bin")
-	if !isInPath(binDir) {
+	// Only worth saying to someone at a terminal, not in a scheduled job's log
+	if !isInPath(binDir) && IsTerminal(os.Stdout) {
 		color.Yellow("\nImportant: neomanager bin directory is not in your PATH")
 		fmt.Printf("\nAdd this line to your shell configuration file (.zshrc, .bashrc, etc.):\n")
-		fmt.Printf("export PATH=\"%s:$PATH\"\n", binDir)
+		switch shell := filepath.Base(os.Getenv("SHELL")); shell {
+		case "fish":
+			fmt.Printf("nea init fish | source\n")
+		case "zsh":
+			fmt.Printf("eval \"$(nea init zsh)\"\n")
+		default:
+			fmt.Printf("eval \"$(nea init bash)\"\n")
+		}
 		fmt.Printf("\nThen restart your terminal or run:\n")
 		fmt.Printf("source ~/.zshrc  # or your shell's config file\n\n")
 	}
@@ -60,22 +67,56 @@ func Setup() error {
 
 // CreateConfigFile creates a config file with default values if it only doesn't exist.
 func createConfigFile() error {
-	if _, err := os.Stat(SymlinkPath); err == nil {
+	if _, err := os.Stat(ConfigPath); err == nil {
 		return nil
 	}
-	defaultConfig := Config{RollbackLimit: 7}
+	defaultConfig := Config{RollbackLimit: DefaultRollbackLimit}
 	configJson, err := json.MarshalIndent(defaultConfig, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to serialize config: %w", err)
 	}
-	if err := os.WriteFile(SymlinkPath, configJson, 0o644); err != nil {
+	if err := os.WriteFile(ConfigPath, configJson, 0o644); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 	return nil
 }
 
 func isInPath(dir string) bool {
-	path := os.Getenv("PATH")
-	paths := strings.Split(path, ":")
-	return slices.Contains(paths, dir)
+	return PathIndex(dir) >= 0
+}
+
+// BinDir is the directory holding the nvim symlink, which belongs on PATH
+func BinDir() string {
+	return filepath.Join(appDir, "bin")
+}
+
+// PathIndex returns the position of dir in PATH, or -1 when it isn't there
+func PathIndex(dir string) int {
+	paths := filepath.SplitList(os.Getenv("PATH"))
+	for i := range paths {
+		paths[i] = filepath.Clean(paths[i])
+	}
+	return slices.Index(paths, filepath.Clean(dir))
+}
+
+// FindInPath returns every executable called name on PATH, in the order
+// the shell looks them up
+func FindInPath(name string) []string {
+	var found []string
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if fi, err := os.Stat(path); err == nil && !fi.IsDir() && fi.Mode()&0o111 != 0 && !slices.Contains(found, path) {
+			found = append(found, path)
+		}
+	}
+	return found
+}
+
+// IsTerminal reports whether file is a terminal
+func IsTerminal(file *os.File) bool {
+	fi, err := file.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
 }
@@ -0,0 +1,374 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/semver"
+)
+
+// Channels a release source can be configured for
+const (
+	ChannelStable  = "stable"
+	ChannelNightly = "nightly"
+)
+
+// ReleaseSource is where releases are listed, downloaded and verified from
+type ReleaseSource interface {
+	// ListTags returns the available tags, newest first
+	ListTags() ([]Release, error)
+	// Release returns the release with the given tag, e.g. "v0.10.4" or "nightly"
+	Release(tag string) (ReleaseInfo, error)
+	// Checksum returns the SHA-256 published for an asset, or "" when there is none
+	Checksum(release ReleaseInfo, asset ReleaseAsset) (string, error)
+}
+
+// SourceConfig selects the release source of a channel in config.json
+type SourceConfig struct {
+	// Type is "github" (the default) or "mirror"
+	Type string `json:"type"`
+	// URL is the mirror's base URL
+	URL string `json:"url,omitempty"`
+	// Index is "json" to read <url>/index.json, or "listing" to read the
+	// mirror's directory listings (<url>/<tag>/<asset>)
+	Index string `json:"index,omitempty"`
+}
+
+// NewReleaseSource builds the source configured for a channel. Metadata is
+// always fetched through the client so it is cached and honors offline mode.
+func NewReleaseSource(client *GitHubClient, config SourceConfig) (ReleaseSource, error) {
+	switch config.Type {
+	case "", "github":
+		return &GitHubSource{Client: client}, nil
+	case "mirror":
+		if config.URL == "" {
+			return nil, fmt.Errorf("mirror source needs a url")
+		}
+		mirror := &MirrorSource{Client: client, BaseURL: strings.TrimSuffix(config.URL, "/"), Index: config.Index}
+		if mirror.Index == "" {
+			mirror.Index = "json"
+		}
+		if mirror.Index != "json" && mirror.Index != "listing" {
+			return nil, fmt.Errorf("unknown mirror index '%s', expected 'json' or 'listing'", config.Index)
+		}
+		return mirror, nil
+	default:
+		return nil, fmt.Errorf("unknown source type '%s', expected 'github' or 'mirror'", config.Type)
+	}
+}
+
+// ReleaseSourcesFromConfig returns the stable and nightly sources from the
+// config file, defaulting to GitHub for both
+func ReleaseSourcesFromConfig(client *GitHubClient) (stable ReleaseSource, nightly ReleaseSource, err error) {
+	config, _ := ReadConfig()
+	if stable, err = NewReleaseSource(client, config.Sources[ChannelStable]); err != nil {
+		return nil, nil, fmt.Errorf("invalid stable source: %w", err)
+	}
+	if nightly, err = NewReleaseSource(client, config.Sources[ChannelNightly]); err != nil {
+		return nil, nil, fmt.Errorf("invalid nightly source: %w", err)
+	}
+	return stable, nightly, nil
+}
+
+func FetchLatestStable(source ReleaseSource) (string, error) {
+	releases, err := source.ListTags()
+	if err != nil {
+		return "", err
+	}
+
+	if len(releases) == 0 {
+		return "", fmt.Errorf("no releases found")
+	}
+
+	// Extract the numeric part of the version name
+	re := regexp.MustCompile("[0-9]+")
+	versionParts := re.FindAllString(releases[0].Name, -1)
+	if len(versionParts) == 0 {
+		return "", fmt.Errorf("failed to extract version number")
+	}
+
+	// Combine the numeric parts to form the version number
+	versionNumber := strings.Join(versionParts, ".")
+
+	return versionNumber, nil
+}
+
+// CommitSource is implemented by sources that know which commit a release
+// was built from
+type CommitSource interface {
+	Commit(release ReleaseInfo) (string, error)
+}
+
+// GitHubSource serves releases straight from the neovim repository
+type GitHubSource struct {
+	Client *GitHubClient
+}
+
+func (g *GitHubSource) ListTags() ([]Release, error) {
+	tags, err := g.Client.FetchReleases()
+	if err != nil {
+		return nil, err
+	}
+	// GitHub lists tags by name, v0.9.5 before v0.10.0
+	SortTagsDesc(tags)
+	return tags, nil
+}
+
+func (g *GitHubSource) Release(tag string) (ReleaseInfo, error) {
+	return g.Client.FetchRelease(tag)
+}
+
+func (g *GitHubSource) Commit(release ReleaseInfo) (string, error) {
+	return g.Client.FetchCommit(release.TagName)
+}
+
+// Checksum uses the asset's digest when GitHub reports one, otherwise the
+// <asset>.sha256sum file published next to it or the shasum.txt covering
+// every asset of newer releases
+func (g *GitHubSource) Checksum(release ReleaseInfo, asset ReleaseAsset) (string, error) {
+	if digest, found := strings.CutPrefix(asset.Digest, "sha256:"); found && digest != "" {
+		return digest, nil
+	}
+	for _, candidate := range release.Assets {
+		if candidate.Name == asset.Name+".sha256sum" || candidate.Name == "shasum.txt" {
+			body, err := g.Client.FetchCached(candidate.BrowserDownloadURL)
+			if err != nil {
+				return "", fmt.Errorf("failed to fetch checksum: %w", err)
+			}
+			return findChecksum(body, asset.Name), nil
+		}
+	}
+	return "", nil
+}
+
+// MirrorSource serves releases from an internal mirror laid out like
+// <url>/<tag>/<asset>, described either by <url>/index.json or by the
+// server's directory listings
+type MirrorSource struct {
+	Client  *GitHubClient
+	BaseURL string
+	Index   string
+}
+
+// mirrorIndex is the format of <url>/index.json
+type mirrorIndex struct {
+	Releases []struct {
+		Tag       string `json:"tag"`
+		NodeID    string `json:"node_id"`
+		Commit    string `json:"commit"`
+		CreatedAt string `json:"created_at"`
+		Assets    []struct {
+			Name   string `json:"name"`
+			URL    string `json:"url"`
+			SHA256 string `json:"sha256"`
+		} `json:"assets"`
+	} `json:"releases"`
+}
+
+var hrefRegex = regexp.MustCompile(`href="([^"?#]+)"`)
+
+func (m *MirrorSource) ListTags() ([]Release, error) {
+	var tags []Release
+	if m.Index == "json" {
+		index, err := m.readIndex()
+		if err != nil {
+			return nil, err
+		}
+		for _, release := range index.Releases {
+			tags = append(tags, Release{Name: release.Tag})
+		}
+		SortTagsDesc(tags)
+		return tags, nil
+	}
+
+	names, _, err := m.readListing(m.BaseURL + "/")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if name, isDir := strings.CutSuffix(name, "/"); isDir {
+			tags = append(tags, Release{Name: name})
+		}
+	}
+	SortTagsDesc(tags)
+	return tags, nil
+}
+
+func (m *MirrorSource) Release(tag string) (ReleaseInfo, error) {
+	if m.Index == "json" {
+		index, err := m.readIndex()
+		if err != nil {
+			return ReleaseInfo{}, err
+		}
+		for _, release := range index.Releases {
+			if release.Tag != tag {
+				continue
+			}
+			info := ReleaseInfo{TagName: tag, NodeID: release.NodeID, CreatedAt: release.CreatedAt}
+			if info.NodeID == "" {
+				// Rebuilt nightlies keep their tag, the date tells them apart
+				info.NodeID = "mirror:" + tag + "@" + release.CreatedAt
+			}
+			for _, asset := range release.Assets {
+				url := asset.URL
+				if url == "" {
+					url = m.BaseURL + "/" + tag + "/" + asset.Name
+				}
+				info.Assets = append(info.Assets, ReleaseAsset{Name: asset.Name, BrowserDownloadURL: url, Digest: "sha256:" + asset.SHA256})
+			}
+			return info, nil
+		}
+		return ReleaseInfo{}, fmt.Errorf("%w: release %s on mirror %s", ErrNotFound, tag, m.BaseURL)
+	}
+
+	names, listing, err := m.readListing(m.BaseURL + "/" + tag + "/")
+	if err != nil {
+		return ReleaseInfo{}, err
+	}
+	if len(names) == 0 {
+		return ReleaseInfo{}, fmt.Errorf("%w: release %s on mirror %s", ErrNotFound, tag, m.BaseURL)
+	}
+	// Listings carry no release metadata. They do show sizes and dates, so
+	// the listing itself changes with every build and identifies it.
+	sum := sha256.Sum256(listing)
+	info := ReleaseInfo{
+		TagName: tag,
+		NodeID:  "mirror:" + hex.EncodeToString(sum[:8]),
+	}
+	for _, name := range names {
+		if !strings.HasSuffix(name, "/") {
+			info.Assets = append(info.Assets, ReleaseAsset{Name: name, BrowserDownloadURL: m.BaseURL + "/" + tag + "/" + name})
+		}
+	}
+	createdAt, found := listingDate(listing)
+	if !found && len(info.Assets) > 0 {
+		// Listings such as python's http.server show no dates
+		createdAt, err = m.Client.LastModified(info.Assets[0].BrowserDownloadURL)
+		found = err == nil
+	}
+	if !found {
+		createdAt = time.Now()
+	}
+	info.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	return info, nil
+}
+
+// Commit is only known when index.json lists it
+func (m *MirrorSource) Commit(release ReleaseInfo) (string, error) {
+	if m.Index != "json" {
+		return "", nil
+	}
+	index, err := m.readIndex()
+	if err != nil {
+		return "", err
+	}
+	for _, indexed := range index.Releases {
+		if indexed.Tag == release.TagName {
+			return indexed.Commit, nil
+		}
+	}
+	return "", nil
+}
+
+// Checksum uses the index's sha256, directory mirrors are expected to keep
+// the checksum files GitHub publishes
+func (m *MirrorSource) Checksum(release ReleaseInfo, asset ReleaseAsset) (string, error) {
+	return (&GitHubSource{Client: m.Client}).Checksum(release, asset)
+}
+
+func (m *MirrorSource) readIndex() (mirrorIndex, error) {
+	var index mirrorIndex
+	body, err := m.Client.FetchCached(m.BaseURL + "/index.json")
+	if err != nil {
+		return index, fmt.Errorf("failed to fetch mirror index: %w", err)
+	}
+	if err = json.Unmarshal(body, &index); err != nil {
+		return index, fmt.Errorf("failed to parse mirror index: %w", err)
+	}
+	return index, nil
+}
+
+// readListing returns the relative entries of an HTML directory listing,
+// directories keeping their trailing slash, along with the raw listing
+func (m *MirrorSource) readListing(url string) ([]string, []byte, error) {
+	body, err := m.Client.FetchCached(url)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch mirror listing: %w", err)
+	}
+
+	var names []string
+	for _, match := range hrefRegex.FindAllStringSubmatch(string(body), -1) {
+		name := match[1]
+		if strings.Contains(strings.TrimSuffix(name, "/"), "/") || strings.HasPrefix(name, "..") {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names, body, nil
+}
+
+// listingDates are the modification times of nginx and Apache listings
+var listingDates = []struct {
+	pattern *regexp.Regexp
+	layout  string
+}{
+	{regexp.MustCompile(`\b\d{2}-[A-Z][a-z]{2}-\d{4} \d{2}:\d{2}\b`), "02-Jan-2006 15:04"},
+	{regexp.MustCompile(`\b\d{4}-\d{2}-\d{2} \d{2}:\d{2}\b`), "2006-01-02 15:04"},
+}
+
+// listingDate returns the newest modification time shown in a directory
+// listing. Servers print them in UTC.
+func listingDate(listing []byte) (time.Time, bool) {
+	var newest time.Time
+	for _, date := range listingDates {
+		for _, match := range date.pattern.FindAllString(string(listing), -1) {
+			if parsed, err := time.Parse(date.layout, match); err == nil && parsed.After(newest) {
+				newest = parsed
+			}
+		}
+	}
+	return newest, !newest.IsZero()
+}
+
+// SortTagsDesc orders version tags from newest to oldest, other tags such
+// as "nightly" go last
+func SortTagsDesc(tags []Release) {
+	sort.SliceStable(tags, func(i, j int) bool {
+		vi, vj := tags[i].Name, tags[j].Name
+		if !strings.HasPrefix(vi, "v") {
+			vi = "v" + vi
+		}
+		if !strings.HasPrefix(vj, "v") {
+			vj = "v" + vj
+		}
+		return semver.Compare(vi, vj) > 0
+	})
+}
+
+// findChecksum looks for an asset in sha256sum output ("<hash>  <name>"),
+// a file holding a single hash is taken as is
+func findChecksum(body []byte, name string) string {
+	scanner := bufio.NewScanner(strings.NewReader(string(body)))
+	var lines [][]string
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) > 0 {
+			lines = append(lines, fields)
+		}
+	}
+	for _, fields := range lines {
+		if len(fields) >= 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return fields[0]
+		}
+	}
+	if len(lines) == 1 {
+		return lines[0][0]
+	}
+	return ""
+}
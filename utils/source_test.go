@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListingDate(t *testing.T) {
+	tests := []struct {
+		name    string
+		listing string
+		want    string
+	}{
+		{"nginx", `<a href="nvim-linux-x86_64.tar.gz">nvim-linux-x86_64.tar.gz</a>      01-Mar-2025 04:12            11893412
+<a href="shasum.txt">shasum.txt</a>                    01-Mar-2025 04:13                 512`, "2025-03-01T04:13:00Z"},
+		{"apache", `<td><a href="nvim.appimage">nvim.appimage</a></td><td align="right">2025-02-28 23:59  </td><td align="right"> 11M</td>`, "2025-02-28T23:59:00Z"},
+		{"no dates", `<li><a href="nvim-linux-x86_64.tar.gz">nvim-linux-x86_64.tar.gz</a></li>`, ""},
+	}
+	for _, tt := range tests {
+		got, found := listingDate([]byte(tt.listing))
+		if tt.want == "" {
+			if found {
+				t.Errorf("%s: found %s in a listing without dates", tt.name, got)
+			}
+			continue
+		}
+		if !found || got.Format(time.RFC3339) != tt.want {
+			t.Errorf("%s: listingDate = %s, %v, want %s", tt.name, got.Format(time.RFC3339), found, tt.want)
+		}
+	}
+}
+
+func TestGitHubSourceListsNewestFirst(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"name":"v0.9.5"},{"name":"v0.11.0"},{"name":"stable"},{"name":"v0.10.4"},{"name":"nightly"}]`))
+	}))
+	defer server.Close()
+	client := NewGitHubClient("")
+	client.HTTPClient = server.Client()
+	client.BaseURL = server.URL
+
+	tags, err := (&GitHubSource{Client: client}).ListTags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	if want := "v0.11.0 v0.10.4 v0.9.5 stable nightly"; strings.Join(names, " ") != want {
+		t.Errorf("ListTags = %s, want %s", strings.Join(names, " "), want)
+	}
+	if latest, err := FetchLatestStable(&GitHubSource{Client: client}); err != nil || latest != "0.11.0" {
+		t.Errorf("FetchLatestStable = %s, %v, want 0.11.0", latest, err)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package utils
+
+import "errors"
+
+var errNoTerminal = errors.New("terminal control is not supported on this platform")
+
+// MakeRaw is only supported on Linux and the BSDs
+func MakeRaw(fd int) (func() error, error) {
+	return nil, errNoTerminal
+}
+
+// TerminalSize is only supported on Linux and the BSDs
+func TerminalSize(fd int) (int, int, error) {
+	return 0, 0, errNoTerminal
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// VersionFileName is the file the shell integration looks for when changing
+// directories, it holds a version 'nea use' accepts
+const VersionFileName = ".nvim-version"
+
+// FindVersionFile looks for .nvim-version in dir and its parents
+func FindVersionFile(dir string) (string, error) {
+	return findUpwards(dir, VersionFileName)
+}
+
+// ReadVersionFile returns the version of a .nvim-version, its first line
+// that isn't blank or a # comment
+func ReadVersionFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line != "" {
+			return line, nil
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return "", fmt.Errorf("%s is empty", path)
+}
@@ -0,0 +1,204 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// A version range selects the newest version matching all of its parts:
+//
+//	0.10           any 0.10.x
+//	^0.10, ^1.2.3  compatible versions, up to the next breaking one
+//	~0.10.2        patch releases of 0.10 from 0.10.2
+//	>=0.9 <0.11    comparisons, separated by spaces or commas
+
+var partialVersion = regexp.MustCompile(`^v?([0-9]+)(?:\.([0-9]+))?(?:\.([0-9]+))?$`)
+
+// comparator is one bound of a range, version being canonical like v0.10.0
+type comparator struct {
+	op      string
+	version string
+}
+
+// VersionRange is a parsed range, see ParseVersionRange
+type VersionRange []comparator
+
+// IsVersionRange reports whether spec is a range rather than an exact
+// version or a keyword
+func IsVersionRange(spec string) bool {
+	spec = strings.TrimSpace(spec)
+	if strings.ContainsAny(spec, "^~<>=, ") {
+		return true
+	}
+	match := partialVersion.FindStringSubmatch(spec)
+	return match != nil && match[3] == ""
+}
+
+// IsVersion reports whether spec is an exact version, with or without its v
+func IsVersion(spec string) bool {
+	match := partialVersion.FindStringSubmatch(strings.TrimSpace(spec))
+	return match != nil && match[3] != ""
+}
+
+// ParseVersionRange parses a range such as "0.10", "^0.10" or ">=0.9 <0.11"
+func ParseVersionRange(spec string) (VersionRange, error) {
+	var versionRange VersionRange
+	for _, part := range strings.FieldsFunc(spec, func(r rune) bool { return r == ' ' || r == ',' }) {
+		op := ""
+		for _, prefix := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+			if rest, found := strings.CutPrefix(part, prefix); found {
+				op, part = prefix, rest
+				break
+			}
+		}
+		match := partialVersion.FindStringSubmatch(part)
+		if match == nil {
+			return nil, fmt.Errorf("invalid version range '%s'", spec)
+		}
+		major, _ := strconv.Atoi(match[1])
+		minor, _ := strconv.Atoi(match[2])
+		patch, _ := strconv.Atoi(match[3])
+		// precision is how many of major, minor and patch were given
+		precision := 1
+		if match[3] != "" {
+			precision = 3
+		} else if match[2] != "" {
+			precision = 2
+		}
+		lower := fmt.Sprintf("v%d.%d.%d", major, minor, patch)
+
+		// next is the first version past the given one at that precision
+		next := func(precision int) string {
+			switch precision {
+			case 1:
+				return fmt.Sprintf("v%d.0.0", major+1)
+			case 2:
+				return fmt.Sprintf("v%d.%d.0", major, minor+1)
+			}
+			return fmt.Sprintf("v%d.%d.%d", major, minor, patch+1)
+		}
+
+		switch op {
+		case "", "=":
+			if precision == 3 {
+				versionRange = append(versionRange, comparator{"=", lower})
+			} else {
+				versionRange = append(versionRange, comparator{">=", lower}, comparator{"<", next(precision)})
+			}
+		case "^":
+			// The first non-zero part given is the one that can't change
+			upper := next(1)
+			if major == 0 && precision > 1 {
+				upper = next(2)
+				if minor == 0 && precision > 2 {
+					upper = next(3)
+				}
+			}
+			versionRange = append(versionRange, comparator{">=", lower}, comparator{"<", upper})
+		case "~":
+			versionRange = append(versionRange, comparator{">=", lower}, comparator{"<", next(min(precision, 2))})
+		case ">":
+			versionRange = append(versionRange, comparator{">=", next(precision)})
+		case "<=":
+			versionRange = append(versionRange, comparator{"<", next(precision)})
+		default:
+			versionRange = append(versionRange, comparator{op, lower})
+		}
+	}
+	if len(versionRange) == 0 {
+		return nil, fmt.Errorf("empty version range")
+	}
+	return versionRange, nil
+}
+
+// Match reports whether a version, with or without its v, is in the range.
+// Prereleases never match.
+func (r VersionRange) Match(version string) bool {
+	version = "v" + strings.TrimPrefix(version, "v")
+	if !semver.IsValid(version) || semver.Prerelease(version) != "" {
+		return false
+	}
+	for _, c := range r {
+		cmp := semver.Compare(version, c.version)
+		var ok bool
+		switch c.op {
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		default:
+			ok = cmp == 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Highest returns the newest of the versions in the range
+func (r VersionRange) Highest(versions []string) (string, bool) {
+	best := ""
+	for _, version := range versions {
+		if r.Match(version) && (best == "" || semver.Compare("v"+strings.TrimPrefix(version, "v"), "v"+best) > 0) {
+			best = strings.TrimPrefix(version, "v")
+		}
+	}
+	return best, best != ""
+}
+
+// ResolveInstalledVersion resolves a version that has to be installed.
+// "stable", "latest" and "previous-stable" are the releases of source, as
+// for install; when "stable" or "latest" isn't installed or can't be
+// listed, the newest installed stands in for it and notice, when set, is
+// told so. A range is its newest installed match and an alias what it
+// stands for.
+func ResolveInstalledVersion(source ReleaseSource, version string, notice func(message string)) (string, error) {
+	version = ExpandAlias(version)
+	installed, _ := GetLocalStableVersions()
+
+	switch keyword := strings.ToLower(version); keyword {
+	case "stable", "latest", "previous-stable":
+		release, err := ResolveVersion(source, keyword)
+		if err == nil && slices.Contains(installed, release) {
+			return release, nil
+		}
+		if keyword == "previous-stable" || len(installed) == 0 {
+			if err != nil {
+				return "", err
+			}
+			return "", fmt.Errorf("%s is %s, which isn't installed, run 'nea install %s'", keyword, release, keyword)
+		}
+		if notice != nil {
+			if err != nil {
+				notice(fmt.Sprintf("couldn't look up %s (%v), using %s", keyword, err, installed[0]))
+			} else {
+				notice(fmt.Sprintf("%s is %s, which isn't installed, using %s. Run 'nea install %s' to get it.", keyword, release, installed[0], keyword))
+			}
+		}
+		return installed[0], nil
+	}
+
+	if IsVersionRange(version) {
+		versionRange, err := ParseVersionRange(version)
+		if err != nil {
+			return "", err
+		}
+		resolved, found := versionRange.Highest(installed)
+		if !found {
+			return "", fmt.Errorf("no installed version matches %s, run 'nea install %s'", version, version)
+		}
+		return resolved, nil
+	}
+
+	version = strings.TrimPrefix(version, "v")
+	if !exactVersion.MatchString(version) {
+		return "", invalidVersionError()
+	}
+	return version, nil
+}
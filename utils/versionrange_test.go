@@ -0,0 +1,92 @@
+package utils
+
+import "testing"
+
+func TestVersionRangeMatch(t *testing.T) {
+	tests := []struct {
+		spec     string
+		match    []string
+		mismatch []string
+	}{
+		{"0.10", []string{"0.10.0", "0.10.4", "v0.10.4"}, []string{"0.9.5", "0.11.0"}},
+		{"v1", []string{"1.0.0", "1.9.3"}, []string{"0.10.4", "2.0.0"}},
+		{"^0.10", []string{"0.10.0", "0.10.4"}, []string{"0.9.5", "0.11.0"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4", "0.1.0"}},
+		{"^1.2.3", []string{"1.2.3", "1.9.0"}, []string{"1.2.2", "2.0.0"}},
+		{"~0.10.2", []string{"0.10.2", "0.10.9"}, []string{"0.10.1", "0.11.0"}},
+		{"~1", []string{"1.0.0", "1.5.2"}, []string{"2.0.0"}},
+		{">=0.9 <0.11", []string{"0.9.0", "0.10.4"}, []string{"0.8.3", "0.11.0"}},
+		{">=0.9,<0.11", []string{"0.9.5"}, []string{"0.11.0"}},
+		{">0.9", []string{"0.10.0"}, []string{"0.9.0", "0.9.5"}},
+		{"<=0.10", []string{"0.10.4", "0.9.5"}, []string{"0.11.0"}},
+		{"=0.10.4", []string{"0.10.4"}, []string{"0.10.3"}},
+		{"0.10.4", []string{"0.10.4"}, []string{"0.10.5"}},
+		{">=0.10", []string{"0.10.0"}, []string{"0.11.0-dev", "nightly", ""}},
+	}
+	for _, tt := range tests {
+		versionRange, err := ParseVersionRange(tt.spec)
+		if err != nil {
+			t.Errorf("ParseVersionRange(%q): %v", tt.spec, err)
+			continue
+		}
+		for _, version := range tt.match {
+			if !versionRange.Match(version) {
+				t.Errorf("%q should match %s", tt.spec, version)
+			}
+		}
+		for _, version := range tt.mismatch {
+			if versionRange.Match(version) {
+				t.Errorf("%q shouldn't match %s", tt.spec, version)
+			}
+		}
+	}
+}
+
+func TestParseVersionRangeErrors(t *testing.T) {
+	for _, spec := range []string{"", " , ", "^", "0.x", ">=abc", "1.2.3.4", "stable"} {
+		if _, err := ParseVersionRange(spec); err == nil {
+			t.Errorf("ParseVersionRange(%q) should fail", spec)
+		}
+	}
+}
+
+func TestVersionRangeHighest(t *testing.T) {
+	versions := []string{"0.9.5", "v0.10.4", "0.10.2", "0.11.0"}
+	tests := []struct {
+		spec  string
+		want  string
+		found bool
+	}{
+		{"0.10", "0.10.4", true},
+		{"<0.11", "0.10.4", true},
+		{">=0.9", "0.11.0", true},
+		{"^1", "", false},
+	}
+	for _, tt := range tests {
+		versionRange, err := ParseVersionRange(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseVersionRange(%q): %v", tt.spec, err)
+		}
+		if got, found := versionRange.Highest(versions); got != tt.want || found != tt.found {
+			t.Errorf("%q: Highest = %q, %v, want %q, %v", tt.spec, got, found, tt.want, tt.found)
+		}
+	}
+}
+
+func TestIsVersionRange(t *testing.T) {
+	tests := map[string]bool{
+		"0.10":        true,
+		"v1":          true,
+		"^0.10":       true,
+		">=0.9 <0.11": true,
+		"0.10.4":      false,
+		"v0.10.4":     false,
+		"stable":      false,
+		"work":        false,
+	}
+	for spec, want := range tests {
+		if got := IsVersionRange(spec); got != want {
+			t.Errorf("IsVersionRange(%q) = %v, want %v", spec, got, want)
+		}
+	}
+}